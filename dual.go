@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/P-0001/networkquality/internal/color"
+	"github.com/P-0001/networkquality/network"
+)
+
+// dualRows are the metrics -dual shows for each address family
+var dualRows = []struct {
+	name  string
+	value func(r *network.QualityResult) string
+}{
+	{"Download", func(r *network.QualityResult) string { return network.FormatRate(r.DownlinkCapacity) }},
+	{"Upload", func(r *network.QualityResult) string { return network.FormatRate(r.UplinkCapacity) }},
+	{"Idle latency", func(r *network.QualityResult) string { return fmt.Sprintf("%.1f ms", r.IdleLatency) }},
+	{"Jitter", func(r *network.QualityResult) string { return fmt.Sprintf("%.1f ms", r.JitterMs) }},
+	{"Loaded latency", func(r *network.QualityResult) string { return fmt.Sprintf("%.1f ms", r.ResponsivenessMs) }},
+	{"RPM", func(r *network.QualityResult) string { return strconv.Itoa(r.RPM) }},
+	{"Connect", func(r *network.QualityResult) string { return fmt.Sprintf("%.1f ms", r.ConnectMs) }},
+	{"Quality", func(r *network.QualityResult) string {
+		_, label := r.Score()
+		return label
+	}},
+}
+
+// runDual runs the test over IPv4 and then IPv6 and prints the results side
+// by side. A family that could not be measured is shown as unreachable,
+// and the test only fails when both are.
+func runDual(config *network.TestConfig) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	color.Foreground(color.Cyan, true)
+	fmt.Println("Testing over IPv4, then IPv6")
+	color.ResetColor()
+
+	v4, v6, err := network.RunDualStack(ctx, config)
+	if v4 == nil && v6 == nil {
+		color.Foreground(color.Red, true)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		color.ResetColor()
+		os.Exit(1)
+	}
+
+	column := func(r *network.QualityResult, value func(r *network.QualityResult) string) string {
+		if r == nil {
+			return "unreachable"
+		}
+		return value(r)
+	}
+	color.Foreground(color.Magenta, false)
+	fmt.Printf("\n%-16s %14s %14s\n", "", "IPv4", "IPv6")
+	color.Foreground(color.White, false)
+	for _, row := range dualRows {
+		fmt.Printf("%-16s %14s %14s\n", row.name, column(v4, row.value), column(v6, row.value))
+	}
+	color.ResetColor()
+
+	if err != nil {
+		color.Foreground(color.Yellow, true)
+		fmt.Fprintf(os.Stderr, "\nNote: %v\n", err)
+		color.ResetColor()
+	}
+}
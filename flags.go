@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// urlList is a repeatable flag collecting http(s) URLs
+type urlList []string
+
+func (l *urlList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *urlList) Set(value string) error {
+	if err := validateURL(value); err != nil {
+		return err
+	}
+	*l = append(*l, value)
+	return nil
+}
+
+// validateURL checks that value is an absolute http or https URL
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %v", value, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid URL %q: scheme must be http or https", value)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid URL %q: missing host", value)
+	}
+	return nil
+}
+
+// headerList is a repeatable flag collecting "Key: Value" request headers
+type headerList http.Header
+
+func (h headerList) String() string {
+	var parts []string
+	for key, values := range h {
+		for _, v := range values {
+			parts = append(parts, key+": "+v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (h headerList) Set(value string) error {
+	key, val, ok := strings.Cut(value, ":")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("invalid header %q: want \"Key: Value\"", value)
+	}
+	http.Header(h).Add(key, strings.TrimSpace(val))
+	return nil
+}
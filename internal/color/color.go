@@ -0,0 +1,39 @@
+// Package color wraps console text coloring for the CLI. The default build
+// uses go-colortext; building with the nocolor tag drops that dependency and
+// turns every call into a no-op so output is plain text.
+package color
+
+import "os"
+
+// Color is a console foreground color
+type Color int
+
+const (
+	None Color = iota
+	Black
+	Red
+	Green
+	Yellow
+	Blue
+	Magenta
+	Cyan
+	White
+)
+
+// enabled controls whether Foreground and ResetColor have any effect
+var enabled = true
+
+// SetEnabled turns coloring on or off for all subsequent calls
+func SetEnabled(on bool) {
+	enabled = on
+}
+
+// IsTerminal reports whether f is attached to a terminal rather than a
+// file or pipe
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,19 @@
+//go:build !nocolor
+
+package color
+
+import ct "github.com/daviddengcn/go-colortext"
+
+// Foreground changes the foreground color
+func Foreground(c Color, bright bool) {
+	if enabled {
+		ct.Foreground(ct.Color(c), bright)
+	}
+}
+
+// ResetColor restores the original console colors
+func ResetColor() {
+	if enabled {
+		ct.ResetColor()
+	}
+}
@@ -0,0 +1,9 @@
+//go:build nocolor
+
+package color
+
+// Foreground is a no-op in nocolor builds
+func Foreground(c Color, bright bool) {}
+
+// ResetColor is a no-op in nocolor builds
+func ResetColor() {}
@@ -2,34 +2,127 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	ct "github.com/daviddengcn/go-colortext"
+	"github.com/P-0001/networkquality/internal/color"
 	"github.com/P-0001/networkquality/network"
 )
 
 func main() {
 	// Command line flags
-	duration := flag.Int("d", 10, "Test duration in seconds")
+	duration := flag.Int("d", 0, "Total test time in seconds")
+	testDuration := flag.Duration("duration", 0, "Total test time as a Go duration of at least 1s, e.g. 1500ms, 90s, 2m (overrides -d)")
 	connections := flag.Int("c", 4, "Number of parallel connections")
+	autoConnections := flag.Bool("auto-connections", false, "Pick the number of connections by ramping up until throughput stops rising")
 	verbose := flag.Bool("v", false, "Verbose output")
 	quick := flag.Bool("q", false, "Quick test (5 seconds)")
 	help := flag.Bool("h", false, "Show help")
 	version := flag.Bool("version", false, "Show version")
+	ping := flag.Bool("ping", false, "Continuously monitor latency until interrupted")
+	quiet := flag.Bool("quiet", false, "Print only a one-line summary")
+	prometheus := flag.Bool("prometheus", false, "Print results in Prometheus text format")
+	jsonOutput := flag.Bool("json", false, "Print results as versioned JSON")
+	latencyURL := flag.String("latency-url", "", "Server used for latency probes")
+	configPath := flag.String("config", "", "Load test configuration from a JSON file")
+	ipVersion := flag.String("ip", "", "Force IP version: 4 or 6")
+	bidirectional := flag.Bool("bidir", false, "Run download and upload at the same time")
+	noDownload := flag.Bool("no-download", false, "Skip the download phase")
+	noUpload := flag.Bool("no-upload", false, "Skip the upload phase")
+	noColor := flag.Bool("no-color", false, "Disable colored output")
+	debug := flag.Bool("debug", false, "Log requests and phase timings to stderr")
+	threshold := flag.String("threshold", "good", "Lowest acceptable quality for exit status 0: excellent, good, fair, or poor")
+	check := flag.Bool("check", false, "Check that all servers are reachable and exit")
+	sweep := flag.Bool("sweep", false, "Measure download throughput at 1, 2, 4, 8, and 16 connections")
+	webhookURL := flag.String("webhook", "", "POST results as JSON to this URL")
+	csvPath := flag.String("csv", "", "Append results to a CSV file")
+	outputPath := flag.String("output", "", "Also write the results to this file")
+	toStdout := flag.Bool("stdout", true, "Print the results to stdout")
+	dnsServer := flag.String("dns", "", "Resolve server names with this DNS server, e.g. 1.1.1.1")
+	maxMbps := flag.Float64("max-mbps", 0, "Throttle each direction to this rate, for background monitoring (0: no limit)")
+	maxBytes := flag.Int64("max-bytes", 0, "Stop transferring after this many bytes in total (0: no cap)")
+	insecure := flag.Bool("insecure", false, "Skip TLS certificate verification (unsafe)")
+	userAgent := flag.String("user-agent", "", "User-Agent sent with every request (default: networkquality/<version>)")
+	useHTTP3 := flag.Bool("http3", false, "Measure over HTTP/3 (QUIC)")
+	noRedirects := flag.Bool("no-redirects", false, "Treat redirects from test servers as failures")
+	interval := flag.Duration("interval", 0, "Repeat the test at this interval until interrupted, e.g. 15m")
+	historyPath := flag.String("history", "", "With -interval, keep recent results in this JSON file across restarts")
+	repeat := flag.Int("repeat", 0, "Run the test this many times and print a table with the mean and standard deviation")
+	dual := flag.Bool("dual", false, "Run the test over IPv4 and IPv6 and compare them side by side")
+	serve := flag.String("serve", "", "Run a test server on this port or address, e.g. 8080 or 0.0.0.0:8080")
+	proxyURL := flag.String("proxy", "", "HTTP(S) proxy URL (default: HTTP_PROXY/HTTPS_PROXY)")
+	socks5 := flag.String("socks5", "", "Route traffic through a SOCKS5 proxy at host:port, e.g. Tor at 127.0.0.1:9050")
+	socks5RemoteDNS := flag.Bool("socks5-remote-dns", false, "With -socks5, let the proxy resolve server names")
+	var downURLs, upURLs urlList
+	headers := make(headerList)
+	flag.Var(headers, "header", "Extra request header \"Key: Value\" (repeatable)")
+	flag.Var(&downURLs, "down-url", "Download server URL (repeatable, replaces defaults)")
+	flag.Var(&upURLs, "up-url", "Upload server URL (repeatable, replaces defaults)")
 
 	flag.Parse()
 
+	// Honor NO_COLOR (https://no-color.org) and keep escape codes out of
+	// redirected output
+	if *noColor || os.Getenv("NO_COLOR") != "" || !color.IsTerminal(os.Stdout) {
+		color.SetEnabled(false)
+	}
+
+	if *latencyURL != "" {
+		if err := validateURL(*latencyURL); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -latency-url: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if _, ok := qualityRank[strings.ToLower(*threshold)]; !ok {
+		fmt.Fprintf(os.Stderr, "Error: -threshold must be excellent, good, fair, or poor, got %q\n", *threshold)
+		os.Exit(2)
+	}
+	if *interval < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -interval must be positive, got %v\n", *interval)
+		os.Exit(2)
+	}
+	if *repeat < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -repeat must be positive, got %d\n", *repeat)
+		os.Exit(2)
+	}
+	if *repeat > 0 && *interval > 0 {
+		fmt.Fprintln(os.Stderr, "Error: -repeat and -interval cannot be combined")
+		os.Exit(2)
+	}
+	if *dual && (*repeat > 0 || *interval > 0 || *ipVersion != "") {
+		fmt.Fprintln(os.Stderr, "Error: -dual cannot be combined with -repeat, -interval, or -ip")
+		os.Exit(2)
+	}
+	// A side by side comparison has no single result to output
+	if *dual && (*jsonOutput || *prometheus || *quiet || *csvPath != "" || *webhookURL != "" || *outputPath != "") {
+		fmt.Fprintln(os.Stderr, "Error: -dual cannot be combined with -json, -prometheus, -quiet, -csv, -webhook, or -output")
+		os.Exit(2)
+	}
+	if *webhookURL != "" {
+		if err := validateURL(*webhookURL); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -webhook: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
 	if *version {
-		ct.Foreground(ct.Cyan, true)
+		color.Foreground(color.Cyan, true)
 		fmt.Print("networkquality ")
-		ct.Foreground(ct.Green, true)
+		color.Foreground(color.Green, true)
 		fmt.Println("version", network.Version)
-		ct.ResetColor()
+		color.ResetColor()
 		return
 	}
 
@@ -38,48 +131,377 @@ func main() {
 		return
 	}
 
-	testDuration := time.Duration(*duration) * time.Second
+	if *serve != "" {
+		runServe(*serve)
+		return
+	}
+
+	// Flags explicitly passed on the command line override the config file
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	// Configure test
+	config := network.DefaultConfig()
+	if *configPath != "" {
+		loaded, err := network.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		config = loaded
+	}
+	// The environment overrides the config file, and flags override both
+	if err := network.ApplyEnv(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if setFlags["d"] {
+		if *duration <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: -d must be positive, got %d\n", *duration)
+			os.Exit(2)
+		}
+		config.TotalBudget = time.Duration(*duration) * time.Second
+	}
+	if setFlags["duration"] {
+		if *testDuration < network.MinTotalBudget {
+			fmt.Fprintf(os.Stderr, "Error: -duration must be at least %v, got %v\n", network.MinTotalBudget, *testDuration)
+			os.Exit(2)
+		}
+		config.TotalBudget = *testDuration
+	}
 	if *quick {
-		testDuration = 5 * time.Second
+		config.TotalBudget = 5 * time.Second
+	}
+	if setFlags["c"] {
+		config.NumConnections = *connections
+	}
+	if setFlags["auto-connections"] {
+		config.AutoConnections = *autoConnections
+	}
+	if len(downURLs) > 0 {
+		config.TestServers = downURLs
+	}
+	if len(upURLs) > 0 {
+		config.UploadServers = upURLs
+	}
+	if *latencyURL != "" {
+		config.LatencyServer = *latencyURL
+	}
+	if setFlags["ip"] {
+		config.IPVersion = *ipVersion
+	}
+	if setFlags["bidir"] {
+		config.Bidirectional = *bidirectional
+	}
+	if setFlags["no-download"] {
+		config.SkipDownload = *noDownload
+	}
+	if setFlags["no-upload"] {
+		config.SkipUpload = *noUpload
+	}
+	if config.SkipDownload && config.SkipUpload {
+		fmt.Fprintln(os.Stderr, "Error: -no-download and -no-upload cannot be combined")
+		os.Exit(2)
+	}
+	if setFlags["user-agent"] {
+		config.UserAgent = *userAgent
+	}
+	if setFlags["http3"] {
+		config.HTTP3 = *useHTTP3
+	}
+	if setFlags["no-redirects"] {
+		config.FollowRedirects = !*noRedirects
+	}
+	if setFlags["max-mbps"] {
+		config.MaxMbps = *maxMbps
+	}
+	if setFlags["max-bytes"] {
+		config.MaxBytes = *maxBytes
+	}
+	if setFlags["proxy"] {
+		config.ProxyURL = *proxyURL
+	}
+	if setFlags["socks5"] {
+		config.SOCKS5 = *socks5
+	}
+	if setFlags["socks5-remote-dns"] {
+		config.SOCKS5RemoteDNS = *socks5RemoteDNS
+	}
+	if len(headers) > 0 {
+		if config.Headers == nil {
+			config.Headers = make(http.Header)
+		}
+		for key, values := range headers {
+			config.Headers[key] = values
+		}
+	}
+	if *insecure {
+		if config.TLSConfig == nil {
+			config.TLSConfig = &tls.Config{}
+		}
+		config.TLSConfig.InsecureSkipVerify = true
+		color.Foreground(color.Red, true)
+		fmt.Fprintln(os.Stderr, "WARNING: -insecure disables TLS certificate verification; results may come from an impostor server")
+		color.ResetColor()
+	}
+	if *dnsServer != "" {
+		config.Resolver = network.NewResolver(*dnsServer)
+	}
+	if *debug {
+		config.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	} else if *verbose {
+		// Verbose runs still report notable events such as redirects
+		config.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	}
+
+	if *check {
+		runCheck(config)
+		return
+	}
+
+	if *ping {
+		runPing(config)
+		return
+	}
+
+	if *sweep {
+		// Five back-to-back downloads; keep each short unless asked otherwise,
+		// and read a requested duration as per count rather than in total
+		if config.TotalBudget > 0 {
+			config.TestDuration = config.TotalBudget
+			config.TotalBudget = 0
+		} else if *configPath == "" {
+			config.TestDuration = 5 * time.Second
+		}
+		runSweep(config)
+		return
+	}
+
+	if config.NumConnections > network.MaxConnections {
+		color.Foreground(color.Yellow, false)
+		fmt.Fprintf(os.Stderr, "Warning: %d connections exceeds the maximum, using %d\n",
+			config.NumConnections, network.MaxConnections)
+		color.ResetColor()
+	}
+
+	// Machine-readable output formats keep stdout free of anything else
+	format := formatHuman
+	switch {
+	case *prometheus:
+		format = formatPrometheus
+	case *jsonOutput:
+		format = formatJSON
+	case *quiet:
+		format = formatQuiet
+	}
+	humanOutput := format == formatHuman
+	outputs := resultOutputs{
+		format:     format,
+		csvPath:    *csvPath,
+		webhookURL: *webhookURL,
+		outputPath: *outputPath,
+	}
+
+	if *interval > 0 {
+		runMonitor(config, *interval, outputs, *toStdout, *historyPath)
+		return
+	}
+
+	if *repeat > 0 {
+		runRepeat(config, *repeat, outputs, *toStdout)
+		return
+	}
+
+	if *dual {
+		runDual(config)
+		return
 	}
 
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Handle interrupt signal
-	sigChan := make(chan os.Signal, 1)
+	// The first interrupt stops the test and keeps what was measured so
+	// far; a second one exits at once
+	var interrupted atomic.Bool
+	sigChan := make(chan os.Signal, 2)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-sigChan
-		ct.Foreground(ct.Yellow, true)
-		fmt.Println("\n\nTest interrupted by user")
-		ct.ResetColor()
+		interrupted.Store(true)
 		cancel()
-		os.Exit(0)
+		<-sigChan
+		color.ResetColor()
+		os.Exit(exitInterrupted)
 	}()
 
-	// Print header
-	ct.Foreground(ct.Cyan, true)
-	fmt.Println("Networkquality")
-	fmt.Println("==============")
-	ct.ResetColor()
-
-	// Configure test
-	config := network.DefaultConfig()
-	config.TestDuration = testDuration
-	config.NumConnections = *connections
+	if humanOutput {
+		// Print header
+		color.Foreground(color.Cyan, true)
+		fmt.Println("Networkquality")
+		fmt.Println("==============")
+		color.ResetColor()
+	}
 
-	if *verbose {
-		ct.Foreground(ct.Magenta, false)
+	if humanOutput && *verbose {
+		color.Foreground(color.Magenta, false)
 		fmt.Printf("Configuration:\n")
-		ct.Foreground(ct.White, false)
-		fmt.Printf("  Test duration: %v\n", config.TestDuration)
-		fmt.Printf("  Connections: %d\n", config.NumConnections)
-		ct.ResetColor()
+		color.Foreground(color.White, false)
+		if config.TotalBudget > 0 {
+			fmt.Printf("  Total budget: %v\n", config.TotalBudget)
+		} else {
+			fmt.Printf("  Test duration: %v\n", config.TestDuration)
+		}
+		if config.AutoConnections {
+			fmt.Printf("  Connections: auto\n")
+		} else {
+			fmt.Printf("  Connections: %d\n", config.NumConnections)
+		}
+		fmt.Printf("  Warmup: %v\n", config.WarmupDuration)
+		if *dnsServer != "" {
+			fmt.Printf("  DNS server: %s\n", *dnsServer)
+		}
+		if config.TotalBudget == 0 {
+			fmt.Printf("  Download window: %v\n", config.TestDuration)
+			fmt.Printf("  Upload window: %v\n", config.UploadDuration())
+		}
+		color.ResetColor()
 		fmt.Println()
 	}
 
+	// The spinner would interleave with debug logs on the terminal
+	stopSpinner := func(status string) {}
+	if humanOutput && !*debug {
+		stopSpinner = startSpinner(config)
+	}
+
+	startTime := time.Now()
+	result, err := network.RunQualityTest(ctx, config)
+	switch {
+	case interrupted.Load():
+		stopSpinner(statusInterrupted)
+	case err != nil:
+		stopSpinner(statusFailed)
+	default:
+		stopSpinner(statusDone)
+	}
+
+	elapsed := time.Since(startTime)
+
+	if interrupted.Load() {
+		color.Foreground(color.Yellow, true)
+		fmt.Fprintln(os.Stderr, "Test interrupted by user")
+		color.ResetColor()
+		if result == nil {
+			fmt.Fprintln(os.Stderr, "Nothing was measured before the interrupt")
+			os.Exit(exitInterrupted)
+		}
+	}
+
+	if errors.Is(err, network.ErrPartial) && result != nil {
+		color.Foreground(color.Yellow, true)
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		color.ResetColor()
+		err = nil
+	}
+
+	if err != nil {
+		color.Foreground(color.Red, true)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if errors.Is(err, network.ErrCaptivePortal) {
+			fmt.Fprintln(os.Stderr, "Sign in to the network in a browser, then run the test again.")
+		}
+		color.ResetColor()
+		os.Exit(1)
+	}
+
+	if result.ByteCapReached {
+		color.Foreground(color.Yellow, true)
+		fmt.Fprintln(os.Stderr, "Warning: stopped early after reaching the -max-bytes cap")
+		color.ResetColor()
+	}
+
+	// Outputs are still written after an interrupt, so they must not share
+	// the cancelled context
+	report, err := outputs.record(context.WithoutCancel(ctx), result)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch {
+	case !*toStdout:
+		// Results only go to the output file
+	case !humanOutput:
+		os.Stdout.Write(report)
+	default:
+		displayResults(result)
+		if *verbose {
+			displayDetails(result, elapsed)
+		}
+	}
+
+	if interrupted.Load() {
+		os.Exit(exitInterrupted)
+	}
+	_, label := result.Score()
+	os.Exit(qualityExitCode(label, strings.ToLower(*threshold)))
+}
+
+// qualityRank orders score labels from best to worst, keyed by the
+// lowercase names accepted by -threshold
+var qualityRank = map[string]int{
+	strings.ToLower(network.ScoreExcellent): 0,
+	strings.ToLower(network.ScoreGood):      1,
+	strings.ToLower(network.ScoreFair):      2,
+	strings.ToLower(network.ScorePoor):      3,
+}
+
+// Exit statuses for results below the -threshold quality; 1 is kept for
+// test errors and 2 for usage errors
+const (
+	exitBelowGood = 5
+	exitBelowFair = 10
+	exitPoor      = 20
+)
+
+// exitInterrupted is the status after Ctrl-C or SIGTERM, following the
+// shell convention of 128 plus the signal number
+const exitInterrupted = 130
+
+// qualityExitCode returns 0 when label meets threshold, otherwise a status
+// identifying how far the quality fell
+func qualityExitCode(label, threshold string) int {
+	rank := qualityRank[strings.ToLower(label)]
+	if rank <= qualityRank[threshold] {
+		return 0
+	}
+	switch label {
+	case network.ScoreGood:
+		return exitBelowGood
+	case network.ScoreFair:
+		return exitBelowFair
+	default:
+		return exitPoor
+	}
+}
+
+// Final spinner statuses
+const (
+	statusDone        = "done"
+	statusFailed      = "failed"
+	statusInterrupted = "interrupted"
+)
+
+// startSpinner shows a spinner with the elapsed and estimated total time
+// while the test runs, fed by the config's OnProgress. The returned function
+// stops it and prints how the test ended.
+func startSpinner(config *network.TestConfig) func(status string) {
+	estimate := newProgressEstimate(config)
+	config.OnProgress = estimate.update
+
 	spinnerStop := make(chan struct{})
 	spinnerDone := make(chan struct{})
 	go func() {
@@ -89,158 +511,503 @@ func main() {
 		ticker := time.NewTicker(120 * time.Millisecond)
 		defer ticker.Stop()
 
-		ct.Foreground(ct.Yellow, false)
+		color.Foreground(color.Yellow, false)
 		fmt.Print("Running network quality test... ")
+		width := 0
 		for {
 			select {
 			case <-spinnerStop:
-				fmt.Print("\rRunning network quality test...    \r")
-				ct.ResetColor()
+				fmt.Printf("\r%*s\r", width, "")
+				color.ResetColor()
 				return
 			case <-ticker.C:
-				fmt.Printf("\rRunning network quality test... %c", frames[idx%len(frames)])
+				elapsed, total := estimate.times()
+				line := fmt.Sprintf("Running network quality test... %c %v / ~%v",
+					frames[idx%len(frames)], elapsed.Round(time.Second), total.Round(time.Second))
+				// Pad over the rest of a longer previous line
+				fmt.Printf("\r%-*s", width, line)
+				width = max(width, len(line))
 				idx++
 			}
 		}
 	}()
 
-	startTime := time.Now()
-	result, err := network.RunQualityTest(ctx, config)
-	close(spinnerStop)
-	<-spinnerDone
+	return func(status string) {
+		close(spinnerStop)
+		<-spinnerDone
 
-	elapsed := time.Since(startTime)
+		switch status {
+		case statusDone:
+			color.Foreground(color.Green, true)
+		case statusInterrupted:
+			color.Foreground(color.Yellow, true)
+		default:
+			color.Foreground(color.Red, true)
+		}
+		fmt.Printf("Running network quality test... %s\n\n", status)
+		color.ResetColor()
+	}
+}
 
-	if err != nil {
-		ct.Foreground(ct.Red, true)
-		fmt.Printf("Running network quality test... failed\n\n")
-		ct.ResetColor()
+// progressEstimate turns OnProgress reports into an estimate of how long
+// the whole test takes, from the expected length of each phase and how far
+// it has got
+type progressEstimate struct {
+	mu            sync.Mutex
+	start         time.Time
+	expected      map[string]time.Duration
+	fraction      map[string]float64
+	bidirectional bool
+}
+
+func newProgressEstimate(config *network.TestConfig) *progressEstimate {
+	expected := make(map[string]time.Duration)
+	for _, phase := range []string{network.PhaseLatency, network.PhaseDownload, network.PhaseUpload} {
+		expected[phase] = config.PhaseDuration(phase)
+	}
+	return &progressEstimate{
+		start:         time.Now(),
+		expected:      expected,
+		fraction:      make(map[string]float64),
+		bidirectional: config.Bidirectional,
+	}
+}
+
+// update records the progress of a phase, as an OnProgress callback
+func (p *progressEstimate) update(phase string, fraction float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fraction[phase] = fraction
+}
+
+// times returns the time elapsed so far and the estimated total. A phase
+// running over its expected length only extends the total as it goes.
+func (p *progressEstimate) times() (elapsed, total time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	remaining := func(phase string) time.Duration {
+		return time.Duration(float64(p.expected[phase]) * (1 - p.fraction[phase]))
+	}
+
+	rest := remaining(network.PhaseLatency)
+	if p.bidirectional {
+		rest += max(remaining(network.PhaseDownload), remaining(network.PhaseUpload))
 	} else {
-		ct.Foreground(ct.Green, true)
-		fmt.Printf("Running network quality test... done\n\n")
-		ct.ResetColor()
+		rest += remaining(network.PhaseDownload) + remaining(network.PhaseUpload)
+	}
+	elapsed = time.Since(p.start)
+	return elapsed, elapsed + rest
+}
+
+// displayDetails prints the verbose breakdown that follows the summary
+func displayDetails(result *network.QualityResult, elapsed time.Duration) {
+	color.Foreground(color.Magenta, false)
+	fmt.Printf("\nMeasurement windows:\n")
+	color.Foreground(color.White, false)
+	fmt.Printf("  Download: %.2fs measured (configured %v)\n",
+		result.DownloadWindow.Effective.Seconds(), result.DownloadWindow.Configured)
+	fmt.Printf("  Upload:   %.2fs measured (configured %v)\n",
+		result.UploadWindow.Effective.Seconds(), result.UploadWindow.Configured)
+	fmt.Printf("  Connections: %d\n", result.Connections)
+	color.Foreground(color.Magenta, false)
+	fmt.Printf("\nIdle latency breakdown:\n")
+	color.Foreground(color.White, false)
+	fmt.Printf("  DNS %.3f ms  Connect %.3f ms  TLS %.3f ms  TTFB %.3f ms\n",
+		result.DNSMs, result.ConnectMs, result.TLSMs, result.TTFBMs)
+	if result.TLSVersion != "" {
+		fmt.Printf("  %s  %s\n", result.TLSVersion, result.CipherSuite)
+	}
+	if result.ServerProcessingMs > 0 {
+		fmt.Printf("  Server processing %.3f ms  Network-only latency %.3f ms\n",
+			result.ServerProcessingMs, result.AdjustedLatencyMs)
+	}
+	color.Foreground(color.Magenta, false)
+	fmt.Printf("\nIdle latency distribution:\n")
+	color.Foreground(color.White, false)
+	fmt.Printf("  p50 %.3f / p95 %.3f / p99 %.3f ms\n",
+		result.LatencyP50Ms, result.LatencyP95Ms, result.LatencyP99Ms)
+	color.Foreground(color.Magenta, false)
+	fmt.Printf("\nUnder load:\n")
+	color.Foreground(color.White, false)
+	fmt.Printf("  p95 latency %.3f ms  Time to first byte %.3f ms\n",
+		result.LoadedLatencyP95Ms, result.LoadedTTFBMs)
+	color.Foreground(color.Magenta, false)
+	fmt.Printf("\nFailed requests:\n")
+	color.Foreground(color.White, false)
+	fmt.Printf("  Latency: %d  Download: %d  Upload: %d\n",
+		result.LatencyErrors, result.DownloadErrors, result.UploadErrors)
+	fmt.Printf("  Estimated loss under load: %.1f%%\n", result.EstimatedLossPercent)
+	color.Foreground(color.Magenta, false)
+	fmt.Printf("\nDownload throughput:\n")
+	color.Foreground(color.White, false)
+	fmt.Printf("  p50 %.3f / p90 %.3f / peak %.3f Mbps\n",
+		result.DownlinkP50, result.DownlinkP90, result.DownlinkPeak)
+	fmt.Printf("  average %.3f Mbps including ramp-up\n", result.DownlinkAverage)
+	if result.ServerLocation != "" {
+		fmt.Printf("  Served from %s\n", result.ServerLocation)
+	}
+	color.Foreground(color.Magenta, false)
+	fmt.Printf("\nUpload throughput:\n")
+	color.Foreground(color.White, false)
+	fmt.Printf("  p90 %.3f / peak %.3f Mbps\n", result.UplinkP90, result.UplinkPeak)
+	if len(result.DownlinkPerConnection) > 0 {
+		color.Foreground(color.Magenta, false)
+		fmt.Printf("\nDownload per connection:\n")
+		color.Foreground(color.White, false)
+		for i, mbps := range result.DownlinkPerConnection {
+			fmt.Printf("  #%d: %.3f Mbps\n", i+1, mbps)
+		}
+	}
+	if len(result.DownlinkBursts) > 0 {
+		color.Foreground(color.Magenta, false)
+		fmt.Printf("\nDownload bursts:\n")
+		color.Foreground(color.White, false)
+		for i, mbps := range result.DownlinkBursts {
+			fmt.Printf("  #%d: %.3f Mbps\n", i+1, mbps)
+		}
+	}
+	if len(result.UplinkPerChunkSize) > 0 {
+		color.Foreground(color.Magenta, false)
+		fmt.Printf("\nUpload per chunk size:\n")
+		color.Foreground(color.White, false)
+		sizes := make([]int, 0, len(result.UplinkPerChunkSize))
+		for size := range result.UplinkPerChunkSize {
+			sizes = append(sizes, size)
+		}
+		slices.Sort(sizes)
+		for _, size := range sizes {
+			fmt.Printf("  %d bytes: %.3f Mbps\n", size, result.UplinkPerChunkSize[size])
+		}
+	}
+	color.Foreground(color.Magenta, false)
+	fmt.Printf("\nTest completed in %.2f seconds\n", elapsed.Seconds())
+	color.ResetColor()
+}
+
+// runCheck verifies every configured server responds, exiting with status 1
+// if any does not
+func runCheck(config *network.TestConfig) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := network.Validate(ctx, config); err != nil {
+		color.Foreground(color.Red, true)
+		fmt.Fprintln(os.Stderr, "Unreachable servers:")
+		color.ResetColor()
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "  %s\n", line)
+		}
+		os.Exit(1)
 	}
 
+	color.Foreground(color.Green, true)
+	fmt.Println("All servers reachable")
+	color.ResetColor()
+}
+
+// runSweep measures download throughput at each default connection count
+// and prints a table, marking the fastest count
+func runSweep(config *network.TestConfig) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	color.Foreground(color.Cyan, true)
+	fmt.Printf("Sweeping connection counts (%v download each)\n", config.TestDuration)
+	color.ResetColor()
+
+	results, err := network.ConnectionSweep(ctx, config, network.DefaultSweepCounts)
+	if err != nil && !errors.Is(err, network.ErrPartial) {
+		color.Foreground(color.Red, true)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		color.ResetColor()
+		os.Exit(1)
+	}
+
+	best := 0
+	for _, n := range network.DefaultSweepCounts {
+		if mbps, ok := results[n]; ok && (best == 0 || mbps > results[best]) {
+			best = n
+		}
+	}
+
+	color.Foreground(color.Magenta, false)
+	fmt.Printf("\n%-12s %s\n", "Connections", "Download")
+	for _, n := range network.DefaultSweepCounts {
+		mbps, ok := results[n]
+		if !ok {
+			continue
+		}
+		color.Foreground(color.White, n == best)
+		fmt.Printf("%-12d %.3f Mbps", n, mbps)
+		if n == best {
+			fmt.Print("  (best)")
+		}
+		fmt.Println()
+	}
+	color.ResetColor()
+
+	if err != nil {
+		color.Foreground(color.Yellow, true)
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		color.ResetColor()
+	}
+}
+
+// runPing continuously probes latency, updating a single line in place, and
+// prints a summary once interrupted
+func runPing(config *network.TestConfig) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	color.Foreground(color.Cyan, true)
+	fmt.Printf("Pinging %s (Ctrl-C to stop)\n", config.LatencyURL())
+	color.ResetColor()
+
+	stats, err := network.Ping(ctx, config, time.Second, func(s network.PingStats) {
+		color.Foreground(color.White, true)
+		fmt.Printf("\rcur %.1f ms  min %.1f  avg %.1f  max %.1f  jitter %.1f  failed %d/%d   ",
+			s.Last, s.Min, s.Avg, s.Max, s.Jitter, s.Failed, s.Sent)
+		color.ResetColor()
+	})
+	fmt.Println()
+
+	color.Foreground(color.Cyan, true)
+	fmt.Println("\n============ PING =============")
+	color.ResetColor()
+	color.Foreground(color.Green, false)
+	fmt.Print("Probes: ")
+	color.Foreground(color.White, true)
+	fmt.Printf("%d sent, %d failed\n", stats.Sent, stats.Failed)
+	color.Foreground(color.Green, false)
+	fmt.Print("Latency: ")
+	color.Foreground(color.White, true)
+	fmt.Printf("min %.3f / avg %.3f / max %.3f milliseconds\n", stats.Min, stats.Avg, stats.Max)
+	color.Foreground(color.Green, false)
+	fmt.Print("Jitter: ")
+	color.Foreground(color.White, true)
+	fmt.Printf("%.3f milliseconds\n", stats.Jitter)
+	color.ResetColor()
+
 	if err != nil {
-		ct.Foreground(ct.Red, true)
+		color.Foreground(color.Red, true)
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		ct.ResetColor()
+		color.ResetColor()
 		os.Exit(1)
 	}
+}
+
+// runServe runs a test server for other machines to measure against until
+// interrupted
+func runServe(addr string) {
+	// A bare port listens on all interfaces
+	if !strings.Contains(addr, ":") {
+		addr = ":" + addr
+	}
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           network.NewServerHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
 
-	// Display results
-	displayResults(result)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
 
-	if *verbose {
-		ct.Foreground(ct.Magenta, false)
-		fmt.Printf("\nTest completed in %.2f seconds\n", elapsed.Seconds())
-		ct.ResetColor()
+	color.Foreground(color.Cyan, true)
+	fmt.Printf("Serving test endpoints on %s (Ctrl-C to stop)\n", addr)
+	color.ResetColor()
+	fmt.Println("Measure against this machine from a peer with:")
+	fmt.Println("  networkquality -down-url http://<host>" + addr + "/down?bytes=10000000 \\")
+	fmt.Println("    -up-url http://<host>" + addr + "/up -latency-url http://<host>" + addr + "/ping")
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		color.Foreground(color.Red, true)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		color.ResetColor()
+		os.Exit(1)
 	}
 }
 
 func displayResults(result *network.QualityResult) {
 	// Display results in the same format as the screenshot
-	ct.Foreground(ct.Cyan, true)
+	color.Foreground(color.Cyan, true)
 	fmt.Println("\n=========== SUMMARY ===========")
-	ct.ResetColor()
+	color.ResetColor()
 	
-	ct.Foreground(ct.Green, false)
+	color.Foreground(color.Green, false)
 	fmt.Print("Uplink capacity: ")
-	ct.Foreground(ct.White, true)
-	fmt.Printf("%.3f Mbps\n", result.UplinkCapacity)
-	ct.ResetColor()
+	color.Foreground(color.White, true)
+	fmt.Println(network.FormatRate(result.UplinkCapacity))
+	color.ResetColor()
 	
-	ct.Foreground(ct.Green, false)
+	color.Foreground(color.Green, false)
 	fmt.Print("Downlink capacity: ")
-	ct.Foreground(ct.White, true)
-	fmt.Printf("%.3f Mbps\n", result.DownlinkCapacity)
-	ct.ResetColor()
+	color.Foreground(color.White, true)
+	fmt.Println(network.FormatRate(result.DownlinkCapacity))
+	color.ResetColor()
 	
-	ct.Foreground(ct.Green, false)
+	color.Foreground(color.Green, false)
 	fmt.Print("Responsiveness: ")
-	ct.Foreground(ct.White, true)
-	fmt.Printf("%s (%.3f milliseconds)\n",
-		result.Responsiveness, result.ResponsivenessMs)
-	ct.ResetColor()
+	color.Foreground(color.White, true)
+	fmt.Printf("%s (%.3f milliseconds, %d RPM)\n",
+		result.Responsiveness, result.ResponsivenessMs, result.RPM)
+	color.ResetColor()
 	
-	ct.Foreground(ct.Green, false)
+	if result.UploadLoadedLatencyMs > 0 {
+		color.Foreground(color.Green, false)
+		fmt.Print("Upload latency: ")
+		color.Foreground(color.White, true)
+		fmt.Printf("%.3f milliseconds\n", result.UploadLoadedLatencyMs)
+		color.ResetColor()
+	}
+
+	if result.BidirectionalLatencyMs > 0 {
+		color.Foreground(color.Green, false)
+		fmt.Print("Bidirectional latency: ")
+		color.Foreground(color.White, true)
+		fmt.Printf("%.3f milliseconds\n", result.BidirectionalLatencyMs)
+		color.ResetColor()
+	}
+
+	color.Foreground(color.Green, false)
 	fmt.Print("Idle Latency: ")
-	ct.Foreground(ct.White, true)
-	fmt.Printf("%.3f milliseconds\n", result.IdleLatency)
-	ct.ResetColor()
+	color.Foreground(color.White, true)
+	fmt.Printf("%.3f milliseconds", result.IdleLatency)
+	if result.WarmLatencyMs > 0 {
+		fmt.Printf(" (%.3f on a warm connection)", result.WarmLatencyMs)
+	}
+	fmt.Println()
+	color.ResetColor()
+	
+	color.Foreground(color.Green, false)
+	fmt.Print("Jitter: ")
+	color.Foreground(color.White, true)
+	fmt.Printf("%.3f milliseconds\n", result.JitterMs)
+	color.ResetColor()
+
+	if len(result.Caveats) > 0 {
+		color.Foreground(color.Yellow, true)
+		fmt.Print("Caveats: ")
+		color.Foreground(color.White, false)
+		fmt.Printf("%s\n", strings.Join(result.Caveats, ", "))
+		color.ResetColor()
+	}
+	if !result.Stabilized {
+		color.Foreground(color.Yellow, false)
+		fmt.Println("Throughput had not clearly settled when the test ended; a longer test (-d) may measure higher.")
+		color.ResetColor()
+	}
+	if result.ShapingSuspected {
+		color.Foreground(color.Yellow, false)
+		fmt.Printf("Possible traffic shaping: %s.\n", result.ShapingReason)
+		color.ResetColor()
+	}
 
 	// Add visual indicator for quality
-	ct.Foreground(ct.Cyan, true)
+	color.Foreground(color.Cyan, true)
 	fmt.Println("\n========== QUALITY ============")
-	ct.ResetColor()
-	quality, qualityColor := calculateOverallQuality(result)
-	ct.Foreground(ct.White, false)
+	color.ResetColor()
+	quality, qualityColor := qualityLabel(result)
+	color.Foreground(color.White, false)
 	fmt.Print("Overall: ")
-	ct.Foreground(qualityColor, true)
+	color.Foreground(qualityColor, true)
 	fmt.Printf("%s\n", quality)
-	ct.ResetColor()
+	color.ResetColor()
+
+	if result.BufferbloatGrade != "" {
+		color.Foreground(color.White, false)
+		fmt.Print("Bufferbloat: ")
+		color.Foreground(bufferbloatColor(result.BufferbloatGrade), true)
+		fmt.Printf("%s", result.BufferbloatGrade)
+		color.Foreground(color.White, false)
+		fmt.Printf(" (+%.3f ms under load, %.1fx idle)\n", result.LatencyIncreaseMs, result.BufferbloatRatio)
+		color.ResetColor()
+	}
+
+	experience := result.ExperienceScores()
+	color.Foreground(color.Cyan, true)
+	fmt.Println("\n========= EXPERIENCE ==========")
+	color.ResetColor()
+	printExperience("Streaming: ", experience.Streaming)
+	printExperience("Gaming:    ", experience.Gaming)
+	printExperience("Video chat:", experience.VideoChat)
 
 	// Performance bars
-	ct.Foreground(ct.Cyan, true)
+	color.Foreground(color.Cyan, true)
 	fmt.Println("\n======== PERFORMANCE ==========")
-	ct.ResetColor()
+	color.ResetColor()
 	
-	ct.Foreground(ct.Blue, false)
+	color.Foreground(color.Blue, false)
 	fmt.Print("Download: ")
-	ct.ResetColor()
+	color.ResetColor()
 	fmt.Printf("%s\n", getPerformanceBar(result.DownlinkCapacity, 100))
 	
-	ct.Foreground(ct.Blue, false)
+	color.Foreground(color.Blue, false)
 	fmt.Print("Upload:   ")
-	ct.ResetColor()
+	color.ResetColor()
 	fmt.Printf("%s\n", getPerformanceBar(result.UplinkCapacity, 50))
 	
-	ct.Foreground(ct.Blue, false)
+	color.Foreground(color.Blue, false)
 	fmt.Print("Latency:  ")
-	ct.ResetColor()
+	color.ResetColor()
 	fmt.Printf("%s\n", getLatencyBar(result.IdleLatency))
 }
 
-func calculateOverallQuality(result *network.QualityResult) (string, ct.Color) {
-	score := 0
-
-	// Score based on download speed
-	if result.DownlinkCapacity > 50 {
-		score += 3
-	} else if result.DownlinkCapacity > 25 {
-		score += 2
-	} else if result.DownlinkCapacity > 10 {
-		score += 1
+// qualityLabel decorates the result's score label for display
+func qualityLabel(result *network.QualityResult) (string, color.Color) {
+	_, label := result.Score()
+	switch label {
+	case network.ScoreExcellent:
+		return "⭐ Excellent", scoreColor(label)
+	case network.ScoreGood:
+		return "✅ Good", scoreColor(label)
+	case network.ScoreFair:
+		return "⚠️  Fair", scoreColor(label)
+	default:
+		return "❌ Poor", scoreColor(label)
 	}
+}
 
-	// Score based on upload speed
-	if result.UplinkCapacity > 20 {
-		score += 3
-	} else if result.UplinkCapacity > 10 {
-		score += 2
-	} else if result.UplinkCapacity > 5 {
-		score += 1
+// scoreColor returns the display color of a score label
+func scoreColor(label string) color.Color {
+	switch label {
+	case network.ScoreExcellent:
+		return color.Green
+	case network.ScoreGood:
+		return color.Cyan
+	case network.ScoreFair:
+		return color.Yellow
+	case network.ExperienceUnknown:
+		return color.White
+	default:
+		return color.Red
 	}
+}
 
-	// Score based on latency
-	if result.IdleLatency < 20 {
-		score += 3
-	} else if result.IdleLatency < 50 {
-		score += 2
-	} else if result.IdleLatency < 100 {
-		score += 1
-	}
+// printExperience prints one activity of the experience section
+func printExperience(activity, label string) {
+	color.Foreground(color.White, false)
+	fmt.Printf("%s ", activity)
+	color.Foreground(scoreColor(label), true)
+	fmt.Println(label)
+	color.ResetColor()
+}
 
-	// Calculate overall quality
-	switch {
-	case score >= 8:
-		return "⭐ Excellent", ct.Green
-	case score >= 6:
-		return "✅ Good", ct.Cyan
-	case score >= 4:
-		return "⚠️  Fair", ct.Yellow
+func bufferbloatColor(grade string) color.Color {
+	switch grade {
+	case "A":
+		return color.Green
+	case "B":
+		return color.Cyan
+	case "C", "D":
+		return color.Yellow
 	default:
-		return "❌ Poor", ct.Red
+		return color.Red
 	}
 }
 
@@ -251,40 +1018,40 @@ func getPerformanceBar(value float64, maxValue float64) string {
 		filled = barLength
 	}
 
-	ct.Foreground(ct.White, false)
+	color.Foreground(color.White, false)
 	fmt.Print("[")
-	ct.ResetColor()
+	color.ResetColor()
 	
 	// Color the filled portion based on performance
-	var barColor ct.Color
+	var barColor color.Color
 	percentage := (value / maxValue) * 100
 	switch {
 	case percentage >= 75:
-		barColor = ct.Green
+		barColor = color.Green
 	case percentage >= 50:
-		barColor = ct.Cyan
+		barColor = color.Cyan
 	case percentage >= 25:
-		barColor = ct.Yellow
+		barColor = color.Yellow
 	default:
-		barColor = ct.Red
+		barColor = color.Red
 	}
 	
-	ct.Foreground(barColor, true)
+	color.Foreground(barColor, true)
 	for i := 0; i < filled; i++ {
 		fmt.Print("█")
 	}
-	ct.ResetColor()
+	color.ResetColor()
 	
-	ct.Foreground(ct.White, false)
+	color.Foreground(color.White, false)
 	for i := filled; i < barLength; i++ {
 		fmt.Print("░")
 	}
 	fmt.Print("]")
-	ct.ResetColor()
+	color.ResetColor()
 	
-	ct.Foreground(ct.White, true)
-	fmt.Printf(" %.2f Mbps", value)
-	ct.ResetColor()
+	color.Foreground(color.White, true)
+	fmt.Printf(" %s", network.FormatRate(value))
+	color.ResetColor()
 	
 	return ""
 }
@@ -301,100 +1068,136 @@ func getLatencyBar(latency float64) string {
 		filled = barLength
 	}
 
-	ct.Foreground(ct.White, false)
+	color.Foreground(color.White, false)
 	fmt.Print("[")
-	ct.ResetColor()
+	color.ResetColor()
 	
 	// Color based on latency (lower is better)
-	var barColor ct.Color
+	var barColor color.Color
 	switch {
 	case latency < 20:
-		barColor = ct.Green
+		barColor = color.Green
 	case latency < 50:
-		barColor = ct.Cyan
+		barColor = color.Cyan
 	case latency < 100:
-		barColor = ct.Yellow
+		barColor = color.Yellow
 	default:
-		barColor = ct.Red
+		barColor = color.Red
 	}
 	
-	ct.Foreground(barColor, true)
+	color.Foreground(barColor, true)
 	for i := 0; i < filled; i++ {
 		fmt.Print("█")
 	}
-	ct.ResetColor()
+	color.ResetColor()
 	
-	ct.Foreground(ct.White, false)
+	color.Foreground(color.White, false)
 	for i := filled; i < barLength; i++ {
 		fmt.Print("░")
 	}
 	fmt.Print("]")
-	ct.ResetColor()
+	color.ResetColor()
 	
-	ct.Foreground(ct.White, true)
+	color.Foreground(color.White, true)
 	fmt.Printf(" %.2f ms", latency)
-	ct.ResetColor()
+	color.ResetColor()
 	
 	return ""
 }
 
 func printHelp() {
-	ct.Foreground(ct.Cyan, true)
+	color.Foreground(color.Cyan, true)
 	fmt.Println("networkquality")
-	ct.Foreground(ct.White, false)
+	color.Foreground(color.White, false)
 	fmt.Println(" - Test network quality and performance")
-	ct.ResetColor()
+	color.ResetColor()
 	
-	ct.Foreground(ct.Yellow, true)
+	color.Foreground(color.Yellow, true)
 	fmt.Println("\nUsage:")
-	ct.ResetColor()
-	ct.Foreground(ct.White, false)
+	color.ResetColor()
+	color.Foreground(color.White, false)
 	fmt.Println("  networkquality [options]")
-	ct.ResetColor()
+	color.ResetColor()
 	
-	ct.Foreground(ct.Yellow, true)
+	color.Foreground(color.Yellow, true)
 	fmt.Println("\nOptions:")
-	ct.ResetColor()
-	ct.Foreground(ct.Green, false)
-	fmt.Print("  -d <seconds>  ")
-	ct.Foreground(ct.White, false)
-	fmt.Println("Test duration in seconds (default: 10)")
-	ct.Foreground(ct.Green, false)
-	fmt.Print("  -c <count>    ")
-	ct.Foreground(ct.White, false)
-	fmt.Println("Number of parallel connections (default: 4)")
-	ct.Foreground(ct.Green, false)
-	fmt.Print("  -q            ")
-	ct.Foreground(ct.White, false)
-	fmt.Println("Quick test (5 seconds)")
-	ct.Foreground(ct.Green, false)
-	fmt.Print("  -v            ")
-	ct.Foreground(ct.White, false)
-	fmt.Println("Verbose output")
-	ct.Foreground(ct.Green, false)
-	fmt.Print("  -h            ")
-	ct.Foreground(ct.White, false)
-	fmt.Println("Show this help message")
-	ct.ResetColor()
+	color.ResetColor()
+	printOption("-d <seconds>", "Total test time in seconds, split across phases")
+	printOption("-duration <d>", "Total test time of at least 1s, e.g. 1500ms, 90s, 2m (overrides -d)")
+	printOption("-c <count>", "Number of parallel connections (default: 4)")
+	printOption("-auto-connections", "Pick the connection count by ramping up 1, 2, 4, ...")
+	printOption("-q", "Quick test (5 seconds)")
+	printOption("-v", "Verbose output")
+	printOption("-quiet", "Print only a one-line summary")
+	printOption("-threshold <q>", "Lowest quality exiting 0: excellent, good, fair, poor (default: good)")
+	printOption("-check", "Check that all servers are reachable and exit")
+	printOption("-ping", "Continuously monitor latency until interrupted")
+	printOption("-sweep", "Compare download throughput at 1-16 connections")
+	printOption("-interval <d>", "Repeat the test every interval until interrupted, e.g. 15m")
+	printOption("-history <path>", "With -interval, keep recent results in a JSON file")
+	printOption("-repeat <n>", "Run the test n times and print a table with mean and stddev; outputs get the mean")
+	printOption("-dual", "Compare IPv4 and IPv6 side by side")
+	printOption("-serve <port>", "Run a test server for measuring between your own machines")
+	printOption("-prometheus", "Print results in Prometheus text format")
+	printOption("-json", "Print results as versioned JSON")
+	printOption("-down-url <url>", "Download server URL (repeatable)")
+	printOption("-up-url <url>", "Upload server URL (repeatable)")
+	printOption("-latency-url <url>", "Server used for latency probes")
+	printOption("-webhook <url>", "POST results as JSON to a URL")
+	printOption("-csv <path>", "Append results to a CSV file")
+	printOption("-output <path>", "Also write the results to a file")
+	printOption("-stdout=false", "Do not print the results to stdout")
+	printOption("-config <path>", "Load test configuration from a JSON file")
+	printOption("-ip 4|6", "Force IPv4 or IPv6 connections")
+	printOption("-bidir", "Run download and upload at the same time")
+	printOption("-no-download", "Skip the download phase")
+	printOption("-no-upload", "Skip the upload phase")
+	printOption("-debug", "Log requests and phase timings to stderr")
+	printOption("-no-color", "Disable colored output (also NO_COLOR)")
+	printOption("-header <k: v>", "Extra request header, e.g. \"Authorization: Bearer x\" (repeatable)")
+	printOption("-dns <server>", "Resolve names with this DNS server, e.g. 1.1.1.1")
+	printOption("-proxy <url>", "Route traffic through an HTTP(S) proxy")
+	printOption("-socks5 <addr>", "Route traffic through a SOCKS5 proxy, e.g. 127.0.0.1:9050")
+	printOption("-socks5-remote-dns", "With -socks5, resolve names at the proxy")
+	printOption("-insecure", "Skip TLS certificate verification (unsafe)")
+	printOption("-user-agent <ua>", "User-Agent sent with every request")
+	printOption("-http3", "Measure over HTTP/3 (QUIC) instead of TCP")
+	printOption("-no-redirects", "Treat redirects from test servers as failures")
+	printOption("-max-bytes <n>", "Cap the bytes transferred by the whole test")
+	printOption("-max-mbps <rate>", "Throttle each direction to stay under this rate")
+	printOption("-h", "Show this help message")
+	color.ResetColor()
 	
-	ct.Foreground(ct.Yellow, true)
+	color.Foreground(color.Yellow, true)
 	fmt.Println("\nExamples:")
-	ct.ResetColor()
-	ct.Foreground(ct.Cyan, false)
+	color.ResetColor()
+	color.Foreground(color.Cyan, false)
 	fmt.Print("  networkquality           ")
-	ct.Foreground(ct.White, false)
+	color.Foreground(color.White, false)
 	fmt.Println("# Run standard test")
-	ct.Foreground(ct.Cyan, false)
+	color.Foreground(color.Cyan, false)
 	fmt.Print("  networkquality -q        ")
-	ct.Foreground(ct.White, false)
+	color.Foreground(color.White, false)
 	fmt.Println("# Run quick test")
-	ct.Foreground(ct.Cyan, false)
+	color.Foreground(color.Cyan, false)
 	fmt.Print("  networkquality -d 30     ")
-	ct.Foreground(ct.White, false)
+	color.Foreground(color.White, false)
 	fmt.Println("# Run 30-second test")
-	ct.Foreground(ct.Cyan, false)
+	color.Foreground(color.Cyan, false)
 	fmt.Print("  networkquality -v        ")
-	ct.Foreground(ct.White, false)
+	color.Foreground(color.White, false)
 	fmt.Println("# Run with verbose output")
-	ct.ResetColor()
+	color.Foreground(color.Cyan, false)
+	fmt.Print("  networkquality -ping     ")
+	color.Foreground(color.White, false)
+	fmt.Println("# Monitor latency like ping")
+	color.ResetColor()
+}
+
+// printOption prints one aligned entry of the help option list
+func printOption(name, description string) {
+	color.Foreground(color.Green, false)
+	fmt.Printf("  %-20s", name)
+	color.Foreground(color.White, false)
+	fmt.Println(description)
 }
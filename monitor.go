@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/P-0001/networkquality/internal/color"
+	"github.com/P-0001/networkquality/network"
+)
+
+// monitorTimeFormat stamps each line of the monitor log
+const monitorTimeFormat = "2006-01-02 15:04:05"
+
+// monitorHistorySize is how many recent runs the rolling summary covers
+const monitorHistorySize = 24
+
+// runMonitor repeats the test every interval until interrupted, recording
+// each result to the configured outputs and a rolling history, saved to
+// historyPath when set. Failed runs are logged and the loop carries on,
+// since a monitor should outlive a flaky connection. A run cut short by
+// Ctrl-C is discarded.
+func runMonitor(config *network.TestConfig, interval time.Duration, outputs resultOutputs, toStdout bool, historyPath string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	history := network.NewHistory(monitorHistorySize)
+	if historyPath != "" {
+		loaded, err := network.LoadHistory(historyPath, monitorHistorySize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		history = loaded
+	}
+
+	// Keep connections warm between runs instead of redialing each time
+	if config.Transport == nil && !config.HTTP3 {
+		transport, err := network.NewTransport(config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer transport.CloseIdleConnections()
+		config.Transport = transport
+	}
+
+	if outputs.format == formatHuman && toStdout {
+		color.Foreground(color.Cyan, true)
+		fmt.Printf("Testing every %v (Ctrl-C to stop)\n", interval)
+		color.ResetColor()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := network.RunQualityTest(ctx, config)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			history.Add(result)
+			err = recordMonitorResult(ctx, result, outputs, toStdout)
+			if outputs.format == formatHuman && toStdout {
+				printHistorySummary(history.Summary())
+			}
+		}
+		if err == nil && historyPath != "" {
+			err = history.Save(historyPath)
+		}
+		if err != nil {
+			color.Foreground(color.Red, true)
+			fmt.Fprintf(os.Stderr, "%s Error: %v\n", time.Now().Format(monitorTimeFormat), err)
+			if errors.Is(err, network.ErrCaptivePortal) {
+				fmt.Fprintln(os.Stderr, "Sign in to the network in a browser; testing continues.")
+			}
+			color.ResetColor()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordMonitorResult sends one monitor result to the outputs and prints
+// it, as a timestamped line in the human format
+func recordMonitorResult(ctx context.Context, result *network.QualityResult, outputs resultOutputs, toStdout bool) error {
+	report, err := outputs.record(ctx, result)
+	if err != nil || !toStdout {
+		return err
+	}
+	if outputs.format != formatHuman {
+		_, err = os.Stdout.Write(report)
+		return err
+	}
+
+	_, label := result.Score()
+	color.Foreground(color.White, false)
+	fmt.Printf("%s  down=%.1f up=%.1f lat=%.1f rpm=%d  ",
+		result.Timestamp.Format(monitorTimeFormat), result.DownlinkCapacity, result.UplinkCapacity,
+		result.IdleLatency, result.RPM)
+	color.Foreground(scoreColor(label), true)
+	fmt.Println(label)
+	color.ResetColor()
+	return nil
+}
+
+// printHistorySummary prints the rolling averages and ranges of the recent
+// runs, once there is more than one to summarize
+func printHistorySummary(s network.HistorySummary) {
+	if s.Count < 2 {
+		return
+	}
+	color.Foreground(color.Magenta, false)
+	fmt.Printf("%19s  last %d: down %.1f (%.1f-%.1f) up %.1f (%.1f-%.1f) lat %.1f (%.1f-%.1f)\n", "",
+		s.Count, s.Downlink.Avg, s.Downlink.Min, s.Downlink.Max, s.Uplink.Avg, s.Uplink.Min, s.Uplink.Max,
+		s.IdleLatency.Avg, s.IdleLatency.Min, s.IdleLatency.Max)
+	color.ResetColor()
+}
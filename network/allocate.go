@@ -0,0 +1,38 @@
+package network
+
+import "time"
+
+// Shares of TotalBudget given to each phase when all of them run
+const (
+	latencyBudgetShare  = 0.2
+	downloadBudgetShare = 0.5
+	uploadBudgetShare   = 0.3
+)
+
+// MinTotalBudget is the shortest TotalBudget accepted, the least that
+// leaves every phase a usable window
+const MinTotalBudget = time.Second
+
+// allocateTotalBudget derives the phase durations from TotalBudget so the
+// whole run fits within it. The latency phase gets its share, and the rest
+// is split between download and upload, or given whole to the single
+// throughput window when they run in parallel or one is skipped. Warmup
+// may take at most a quarter of a window.
+func (c *TestConfig) allocateTotalBudget() {
+	c.latencyWindow = time.Duration(float64(c.TotalBudget) * latencyBudgetShare)
+	throughput := c.TotalBudget - c.latencyWindow
+
+	downWindow, upWindow := throughput, throughput
+	if !c.Bidirectional && !c.SkipDownload && !c.SkipUpload {
+		downWindow = time.Duration(float64(throughput) * downloadBudgetShare / (downloadBudgetShare + uploadBudgetShare))
+		upWindow = throughput - downWindow
+	}
+	// Each chunk size gets its own upload run
+	if n := len(c.UploadChunkSizes); n > 1 {
+		upWindow /= time.Duration(n)
+	}
+
+	c.WarmupDuration = min(c.warmup(), min(downWindow, upWindow)/4)
+	c.TestDuration = downWindow - c.WarmupDuration
+	c.UploadDurationFraction = float64(upWindow-c.WarmupDuration) / float64(c.TestDuration)
+}
@@ -0,0 +1,79 @@
+package network
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// byteBudget tracks the bytes a run transfers against TestConfig.MaxBytes.
+// A nil budget is unlimited.
+type byteBudget struct {
+	max  int64
+	used atomic.Int64
+	once sync.Once
+	done chan struct{} // closed once max is reached
+}
+
+// newByteBudget returns a budget of max bytes, or nil when max is not
+// positive
+func newByteBudget(max int64) *byteBudget {
+	if max <= 0 {
+		return nil
+	}
+	return &byteBudget{max: max, done: make(chan struct{})}
+}
+
+// add charges n bytes to the budget
+func (b *byteBudget) add(n int64) {
+	if b != nil && b.used.Add(n) >= b.max {
+		b.once.Do(func() { close(b.done) })
+	}
+}
+
+// exhausted reports whether the budget has been used up
+func (b *byteBudget) exhausted() bool {
+	return b != nil && b.used.Load() >= b.max
+}
+
+// bind returns a context that is also cancelled once the budget is used up,
+// so a phase stops early wherever it is waiting
+func (b *byteBudget) bind(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if b == nil {
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-b.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// reader charges bytes read from r to the budget and ends with io.EOF once
+// it is used up. Concurrent readers may overshoot by one read each.
+func (b *byteBudget) reader(r io.Reader) io.Reader {
+	if b == nil {
+		return r
+	}
+	return &budgetReader{r: r, budget: b}
+}
+
+// budgetReader is the io.Reader returned by byteBudget.reader
+type budgetReader struct {
+	r      io.Reader
+	budget *byteBudget
+}
+
+func (r *budgetReader) Read(p []byte) (int, error) {
+	if r.budget.exhausted() {
+		return 0, io.EOF
+	}
+	n, err := r.r.Read(p)
+	r.budget.add(int64(n))
+	return n, err
+}
@@ -0,0 +1,107 @@
+package network
+
+import (
+	"context"
+	"time"
+)
+
+// defaultBurstCount is used when BurstMode is set without a BurstCount
+const defaultBurstCount = 5
+
+// burstCount returns the number of download bursts in BurstMode
+func (c *TestConfig) burstCount() int {
+	if c.BurstCount > 0 {
+		return c.BurstCount
+	}
+	return defaultBurstCount
+}
+
+// measureDownloadBursts splits the download window into BurstCount back to
+// back bursts, each measured with its own counters over the shared
+// connections, while latency is probed across all of them. The combined
+// stats keep the rate of every burst; only the first burst has a warmup.
+func measureDownloadBursts(ctx context.Context, config *TestConfig, latencyURL string) (transferStats, loadedLatency, error) {
+	count := config.burstCount()
+	start := time.Now()
+	window := config.warmup() + config.TestDuration
+
+	// Probing stops as soon as the bursts do, even if early
+	probeCtx, stopProbes := context.WithCancel(ctx)
+	defer stopProbes()
+	waitProbes := probeUnderLoad(probeCtx, config, latencyURL, start, start.Add(window))
+
+	stopProgress := trackProgress(config, PhaseDownload, start, window)
+	total, err := runBursts(ctx, config, count)
+	stopProgress()
+	stopProbes()
+	loaded := waitProbes()
+	if err != nil {
+		return total, loaded, err
+	}
+
+	bursts := float64(len(total.bursts))
+	total.mbps = toMbps(total.bytes, total.window.Seconds())
+	for i := range total.perConn {
+		total.perConn[i] = round3(total.perConn[i] / bursts)
+	}
+	for i := range total.perServer {
+		total.perServer[i].Mbps = round3(total.perServer[i].Mbps / bursts)
+	}
+	return total, loaded, nil
+}
+
+// runBursts runs count download bursts in turn, stopping early once
+// MaxBytes is used up
+func runBursts(ctx context.Context, config *TestConfig, count int) (transferStats, error) {
+	var total transferStats
+	// A fresh limiter would let every burst start with a full bucket
+	limiter := newRateLimiter(config.MaxMbps)
+	for i := 0; i < count; i++ {
+		burstConfig := *config
+		burstConfig.TestDuration = config.TestDuration / time.Duration(count)
+		burstConfig.OnProgress = nil
+		burstConfig.limiter = limiter
+		if i > 0 {
+			burstConfig.WarmupDuration = 0
+		}
+
+		// Latency is probed across the bursts instead of within each
+		stats, _, err := measureDownloadSpeed(ctx, &burstConfig, "")
+		if err != nil {
+			return total, err
+		}
+		config.debug("download burst finished", "burst", i+1, "mbps", stats.mbps)
+		total.addBurst(stats)
+		if config.budget.exhausted() {
+			break
+		}
+	}
+	return total, nil
+}
+
+// addBurst adds the stats of one download burst to the running total,
+// summing per-connection and per-server rates to be averaged at the end
+func (t *transferStats) addBurst(burst transferStats) {
+	if len(t.bursts) == 0 {
+		t.perConn = make([]float64, len(burst.perConn))
+		t.perServer = make([]ServerThroughput, len(burst.perServer))
+		copy(t.perServer, burst.perServer)
+		for i := range t.perServer {
+			t.perServer[i].Mbps = 0
+		}
+		t.location = burst.location
+	}
+	for i, mbps := range burst.perConn {
+		t.perConn[i] += mbps
+	}
+	for i, server := range burst.perServer {
+		t.perServer[i].Mbps += server.Mbps
+	}
+	t.bursts = append(t.bursts, burst.mbps)
+	t.bytes += burst.bytes
+	t.window += burst.window
+	t.samples = append(t.samples, burst.samples...)
+	t.requests += burst.requests
+	t.errors += burst.errors
+	t.compressed = t.compressed || burst.compressed
+}
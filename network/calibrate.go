@@ -0,0 +1,58 @@
+package network
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// calibrationStep is how long each connection count downloads while
+	// AutoConnections calibrates, after calibrationWarmup
+	calibrationStep   = time.Second
+	calibrationWarmup = 500 * time.Millisecond
+
+	// minConnectionGain is the throughput gain, as a fraction, that
+	// doubling the connections must bring for calibration to go on
+	minConnectionGain = 0.1
+)
+
+// calibrateConnections downloads briefly at 1, 2, 4, ... connections, up to
+// MaxConnections, and returns the count after which doubling raised the
+// throughput by less than minConnectionGain. A count at which every request
+// fails ends calibration at the last count that worked.
+func calibrateConnections(ctx context.Context, config *TestConfig) (int, error) {
+	best, bestMbps := 1, 0.0
+	for n := 1; n <= MaxConnections; n *= 2 {
+		step := *config
+		step.NumConnections = n
+		step.TestDuration = calibrationStep
+		step.WarmupDuration = calibrationWarmup
+		step.BurstMode = false
+		step.OnProgress = nil
+
+		// No latency URL, so no probes compete with the download
+		stats, _, err := measureDownloadSpeed(ctx, &step, "")
+		if err != nil {
+			if n > 1 && ctx.Err() == nil {
+				config.debug("connection calibration stopped", "connections", n, "error", err)
+				break
+			}
+			return 0, err
+		}
+		config.debug("connection calibration step", "connections", n, "mbps", stats.mbps)
+		if bestMbps > 0 && stats.mbps < bestMbps*(1+minConnectionGain) {
+			break
+		}
+		best, bestMbps = n, stats.mbps
+	}
+	return best, nil
+}
+
+// calibrationTime returns how long calibrating connections may take
+func calibrationTime() time.Duration {
+	steps := 0
+	for n := 1; n <= MaxConnections; n *= 2 {
+		steps++
+	}
+	return time.Duration(steps) * (calibrationWarmup + calibrationStep)
+}
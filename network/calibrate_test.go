@@ -0,0 +1,96 @@
+package network
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// overloadTransport answers every request with 503 Service Unavailable once
+// more than limit were in flight at the same time, like a server that
+// turns away clients opening too many connections
+type overloadTransport struct {
+	base       http.RoundTripper
+	limit      int64
+	inFlight   atomic.Int64
+	overloaded atomic.Bool
+}
+
+func (t *overloadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.inFlight.Add(1) > t.limit {
+		t.overloaded.Store(true)
+	}
+	// Lets the other workers of a step start before any request is decided
+	if !t.overloaded.Load() {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if t.overloaded.Load() {
+		t.inFlight.Add(-1)
+		return &http.Response{
+			Status:     "503 Service Unavailable",
+			StatusCode: http.StatusServiceUnavailable,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.inFlight.Add(-1)
+		return nil, err
+	}
+	resp.Body = &doneBody{ReadCloser: resp.Body, done: func() { t.inFlight.Add(-1) }}
+	return resp, nil
+}
+
+// doneBody calls done once when the body is closed
+type doneBody struct {
+	io.ReadCloser
+	done func()
+	once atomic.Bool
+}
+
+func (b *doneBody) Close() error {
+	if b.once.CompareAndSwap(false, true) {
+		b.done()
+	}
+	return b.ReadCloser.Close()
+}
+
+// TestCalibrateConnectionsOverload checks that calibration keeps the last
+// count that worked when a higher one makes every request fail
+func TestCalibrateConnectionsOverload(t *testing.T) {
+	// Each connection is held to a fixed rate, so two are faster than one
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := make([]byte, 32*1024)
+		for r.Context().Err() == nil {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	config := newTestConfig(t)
+	config.TestServers = []string{server.URL}
+	config.AutoConnections = true
+	config.Transport = &overloadTransport{base: http.DefaultTransport, limit: 2}
+	config = prepareTestConfig(t, config)
+
+	n, err := calibrateConnections(context.Background(), config)
+	if err != nil {
+		t.Fatalf("calibrateConnections: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("got %d connections, want 2, the most before requests failed", n)
+	}
+}
@@ -0,0 +1,86 @@
+package network
+
+// DefaultCompareThreshold is the change, in percent, beyond which Compare
+// flags a metric as improved or regressed
+const DefaultCompareThreshold = 10.0
+
+// MetricDiff is the change in a single metric between a baseline and the
+// current result
+type MetricDiff struct {
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	// DeltaPercent is the change relative to the baseline. It is 0 when the
+	// baseline is 0, since no relative change can be computed.
+	DeltaPercent float64 `json:"delta_percent"`
+	Improved     bool    `json:"improved"`
+	Regressed    bool    `json:"regressed"`
+}
+
+// ResultDiff compares a result against a baseline metric by metric
+type ResultDiff struct {
+	ThresholdPercent float64    `json:"threshold_percent"`
+	Downlink         MetricDiff `json:"downlink_capacity"`
+	Uplink           MetricDiff `json:"uplink_capacity"`
+	IdleLatency      MetricDiff `json:"idle_latency"`
+	Jitter           MetricDiff `json:"jitter_ms"`
+	LoadedLatency    MetricDiff `json:"responsiveness_ms"`
+	LatencyIncrease  MetricDiff `json:"latency_increase_ms"`
+	RPM              MetricDiff `json:"rpm"`
+}
+
+// Regressed reports whether any metric regressed beyond the threshold
+func (d ResultDiff) Regressed() bool {
+	for _, m := range d.metrics() {
+		if m.Regressed {
+			return true
+		}
+	}
+	return false
+}
+
+// metrics returns every compared metric
+func (d ResultDiff) metrics() []MetricDiff {
+	return []MetricDiff{d.Downlink, d.Uplink, d.IdleLatency, d.Jitter, d.LoadedLatency, d.LatencyIncrease, d.RPM}
+}
+
+// Compare reports how r changed relative to the baseline other, flagging
+// changes larger than DefaultCompareThreshold
+func (r *QualityResult) Compare(other *QualityResult) ResultDiff {
+	return r.CompareThreshold(other, DefaultCompareThreshold)
+}
+
+// CompareThreshold is like Compare but flags changes larger than
+// thresholdPercent, e.g. 20 to catch a 20% drop in download capacity
+func (r *QualityResult) CompareThreshold(other *QualityResult, thresholdPercent float64) ResultDiff {
+	if other == nil {
+		other = &QualityResult{}
+	}
+	return ResultDiff{
+		ThresholdPercent: thresholdPercent,
+		Downlink:         diffMetric(other.DownlinkCapacity, r.DownlinkCapacity, true, thresholdPercent),
+		Uplink:           diffMetric(other.UplinkCapacity, r.UplinkCapacity, true, thresholdPercent),
+		IdleLatency:      diffMetric(other.IdleLatency, r.IdleLatency, false, thresholdPercent),
+		Jitter:           diffMetric(other.JitterMs, r.JitterMs, false, thresholdPercent),
+		LoadedLatency:    diffMetric(other.ResponsivenessMs, r.ResponsivenessMs, false, thresholdPercent),
+		LatencyIncrease:  diffMetric(other.LatencyIncreaseMs, r.LatencyIncreaseMs, false, thresholdPercent),
+		RPM:              diffMetric(float64(other.RPM), float64(r.RPM), true, thresholdPercent),
+	}
+}
+
+// diffMetric compares a single metric, where higherIsBetter tells which
+// direction counts as an improvement
+func diffMetric(baseline, current float64, higherIsBetter bool, thresholdPercent float64) MetricDiff {
+	d := MetricDiff{Baseline: baseline, Current: current}
+	if baseline == 0 {
+		return d
+	}
+
+	d.DeltaPercent = round3((current - baseline) / baseline * 100)
+	change := d.DeltaPercent
+	if !higherIsBetter {
+		change = -change
+	}
+	d.Improved = change > thresholdPercent
+	d.Regressed = change < -thresholdPercent
+	return d
+}
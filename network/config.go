@@ -0,0 +1,252 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// configFile mirrors TestConfig for JSON config files. Pointer fields
+// distinguish omitted values, which keep their DefaultConfig values.
+type configFile struct {
+	TestDuration    *duration         `json:"test_duration"`
+	TotalBudget     *duration         `json:"total_budget"`
+	NumConnections  *int              `json:"num_connections"`
+	AutoConnections *bool             `json:"auto_connections"`
+	TestServers     []string          `json:"test_servers"`
+	UploadServers   []string          `json:"upload_servers"`
+	UploadChunkSize *int              `json:"upload_chunk_size"`
+	ChunkSizes      []int             `json:"upload_chunk_sizes"`
+	BurstMode       *bool             `json:"burst_mode"`
+	BurstCount      *int              `json:"burst_count"`
+	LatencyServer   *string           `json:"latency_server"`
+	SampleInterval  *duration         `json:"sample_interval"`
+	LatencySamples  *int              `json:"latency_samples"`
+	UploadFraction  *float64          `json:"upload_duration_fraction"`
+	WarmupDuration  *duration         `json:"warmup_duration"`
+	LoadProbeDelay  *duration         `json:"load_probe_delay"`
+	RequestTimeout  *duration         `json:"request_timeout"`
+	LatencyTimeout  *duration         `json:"latency_timeout"`
+	RandomPayload   *bool             `json:"random_payload"`
+	MaxRetries      *int              `json:"max_retries"`
+	IPVersion       *string           `json:"ip_version"`
+	Bidirectional   *bool             `json:"bidirectional"`
+	ProxyURL        *string           `json:"proxy_url"`
+	SOCKS5          *string           `json:"socks5"`
+	SOCKS5RemoteDNS *bool             `json:"socks5_remote_dns"`
+	CountWireBytes  *bool             `json:"count_wire_bytes"`
+	CaptivePortal   *string           `json:"captive_portal_url"`
+	Headers         map[string]string `json:"headers"`
+	StreamingUpload *bool             `json:"streaming_upload"`
+	LossProbes      *int              `json:"loss_probes"`
+	LossTimeout     *duration         `json:"loss_probe_timeout"`
+	MaxBytes        *int64            `json:"max_bytes"`
+	MaxMbps         *float64          `json:"max_mbps"`
+	SkipDownload    *bool             `json:"skip_download"`
+	SkipUpload      *bool             `json:"skip_upload"`
+	FollowRedirects *bool             `json:"follow_redirects"`
+	HTTP3           *bool             `json:"http3"`
+	UserAgent       *string           `json:"user_agent"`
+}
+
+// duration is a time.Duration written in config files as a Go duration
+// string such as "15s" or "500ms"
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"15s\", got %s", data)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// LoadConfig reads a JSON config file into a TestConfig. Fields omitted from
+// the file keep their DefaultConfig values.
+func LoadConfig(path string) (*TestConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file configFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if err := file.checkServers(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	config := DefaultConfig()
+	file.apply(config)
+	return config, nil
+}
+
+// checkServers reports the first server URL in the file that is not an
+// http or https URL with a host
+func (f *configFile) checkServers() error {
+	for _, server := range f.TestServers {
+		if err := checkServerURL(server); err != nil {
+			return fmt.Errorf("test_servers %q: %w", server, err)
+		}
+	}
+	for _, server := range f.UploadServers {
+		if err := checkServerURL(server); err != nil {
+			return fmt.Errorf("upload_servers %q: %w", server, err)
+		}
+	}
+	if f.LatencyServer != nil {
+		if err := checkServerURL(*f.LatencyServer); err != nil {
+			return fmt.Errorf("latency_server %q: %w", *f.LatencyServer, err)
+		}
+	}
+	return nil
+}
+
+// checkServerURL reports why value is not an http or https URL with a host
+func checkServerURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return errors.New("must be a URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return errors.New("missing host")
+	}
+	return nil
+}
+
+// apply copies every value set in the file onto config
+func (f *configFile) apply(config *TestConfig) {
+	if f.TestDuration != nil {
+		config.TestDuration = time.Duration(*f.TestDuration)
+	}
+	if f.TotalBudget != nil {
+		config.TotalBudget = time.Duration(*f.TotalBudget)
+	}
+	if f.NumConnections != nil {
+		config.NumConnections = *f.NumConnections
+	}
+	if f.AutoConnections != nil {
+		config.AutoConnections = *f.AutoConnections
+	}
+	if len(f.TestServers) > 0 {
+		config.TestServers = f.TestServers
+	}
+	if len(f.UploadServers) > 0 {
+		config.UploadServers = f.UploadServers
+	}
+	if f.UploadChunkSize != nil {
+		config.UploadChunkSize = *f.UploadChunkSize
+	}
+	if len(f.ChunkSizes) > 0 {
+		config.UploadChunkSizes = f.ChunkSizes
+	}
+	if f.BurstMode != nil {
+		config.BurstMode = *f.BurstMode
+	}
+	if f.BurstCount != nil {
+		config.BurstCount = *f.BurstCount
+	}
+	if f.LatencyServer != nil {
+		config.LatencyServer = *f.LatencyServer
+	}
+	if f.SampleInterval != nil {
+		config.SampleInterval = time.Duration(*f.SampleInterval)
+	}
+	if f.LatencySamples != nil {
+		config.LatencySamples = *f.LatencySamples
+	}
+	if f.UploadFraction != nil {
+		config.UploadDurationFraction = *f.UploadFraction
+	}
+	if f.WarmupDuration != nil {
+		config.WarmupDuration = time.Duration(*f.WarmupDuration)
+	}
+	if f.LoadProbeDelay != nil {
+		config.LoadProbeDelay = time.Duration(*f.LoadProbeDelay)
+	}
+	if f.RequestTimeout != nil {
+		config.RequestTimeout = time.Duration(*f.RequestTimeout)
+	}
+	if f.LatencyTimeout != nil {
+		config.LatencyTimeout = time.Duration(*f.LatencyTimeout)
+	}
+	if f.RandomPayload != nil {
+		config.RandomPayload = *f.RandomPayload
+	}
+	if f.MaxRetries != nil {
+		config.MaxRetries = *f.MaxRetries
+	}
+	if f.IPVersion != nil {
+		config.IPVersion = *f.IPVersion
+	}
+	if f.Bidirectional != nil {
+		config.Bidirectional = *f.Bidirectional
+	}
+	if f.ProxyURL != nil {
+		config.ProxyURL = *f.ProxyURL
+	}
+	if f.SOCKS5 != nil {
+		config.SOCKS5 = *f.SOCKS5
+	}
+	if f.SOCKS5RemoteDNS != nil {
+		config.SOCKS5RemoteDNS = *f.SOCKS5RemoteDNS
+	}
+	if f.CountWireBytes != nil {
+		config.CountWireBytes = *f.CountWireBytes
+	}
+	if f.CaptivePortal != nil {
+		config.CaptivePortalURL = *f.CaptivePortal
+	}
+	if f.StreamingUpload != nil {
+		config.StreamingUpload = *f.StreamingUpload
+	}
+	if f.LossProbes != nil {
+		config.LossProbes = *f.LossProbes
+	}
+	if f.LossTimeout != nil {
+		config.LossProbeTimeout = time.Duration(*f.LossTimeout)
+	}
+	if f.MaxBytes != nil {
+		config.MaxBytes = *f.MaxBytes
+	}
+	if f.MaxMbps != nil {
+		config.MaxMbps = *f.MaxMbps
+	}
+	if f.SkipDownload != nil {
+		config.SkipDownload = *f.SkipDownload
+	}
+	if f.SkipUpload != nil {
+		config.SkipUpload = *f.SkipUpload
+	}
+	if f.FollowRedirects != nil {
+		config.FollowRedirects = *f.FollowRedirects
+	}
+	if f.HTTP3 != nil {
+		config.HTTP3 = *f.HTTP3
+	}
+	if f.UserAgent != nil {
+		config.UserAgent = *f.UserAgent
+	}
+	if f.Headers != nil {
+		config.Headers = make(http.Header, len(f.Headers))
+		for key, value := range f.Headers {
+			config.Headers.Set(key, value)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package network
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvColumn is a result field written as one CSV column
+type csvColumn struct {
+	name  string
+	value func(r *QualityResult) string
+}
+
+// numeric formats a numeric result field for a csvColumn
+func numeric(value func(r *QualityResult) float64) func(r *QualityResult) string {
+	return func(r *QualityResult) string {
+		return strconv.FormatFloat(value(r), 'f', -1, 64)
+	}
+}
+
+// csvColumns lists the columns in file order. New columns must only be
+// appended so rows stay aligned with headers of existing files.
+var csvColumns = []csvColumn{
+	{"uplink_capacity", numeric(func(r *QualityResult) float64 { return r.UplinkCapacity })},
+	{"downlink_capacity", numeric(func(r *QualityResult) float64 { return r.DownlinkCapacity })},
+	{"idle_latency", numeric(func(r *QualityResult) float64 { return r.IdleLatency })},
+	{"jitter_ms", numeric(func(r *QualityResult) float64 { return r.JitterMs })},
+	{"dns_ms", numeric(func(r *QualityResult) float64 { return r.DNSMs })},
+	{"connect_ms", numeric(func(r *QualityResult) float64 { return r.ConnectMs })},
+	{"tls_ms", numeric(func(r *QualityResult) float64 { return r.TLSMs })},
+	{"ttfb_ms", numeric(func(r *QualityResult) float64 { return r.TTFBMs })},
+	{"responsiveness_ms", numeric(func(r *QualityResult) float64 { return r.ResponsivenessMs })},
+	{"rpm", numeric(func(r *QualityResult) float64 { return float64(r.RPM) })},
+	{"bidirectional_latency_ms", numeric(func(r *QualityResult) float64 { return r.BidirectionalLatencyMs })},
+	{"latency_increase_ms", numeric(func(r *QualityResult) float64 { return r.LatencyIncreaseMs })},
+	{"downlink_p50", numeric(func(r *QualityResult) float64 { return r.DownlinkP50 })},
+	{"downlink_p90", numeric(func(r *QualityResult) float64 { return r.DownlinkP90 })},
+	{"downlink_peak", numeric(func(r *QualityResult) float64 { return r.DownlinkPeak })},
+	{"uplink_p90", numeric(func(r *QualityResult) float64 { return r.UplinkP90 })},
+	{"uplink_peak", numeric(func(r *QualityResult) float64 { return r.UplinkPeak })},
+	{"download_window_seconds", numeric(func(r *QualityResult) float64 { return r.DownloadWindow.Effective.Seconds() })},
+	{"upload_window_seconds", numeric(func(r *QualityResult) float64 { return r.UploadWindow.Effective.Seconds() })},
+	{"download_errors", numeric(func(r *QualityResult) float64 { return float64(r.DownloadErrors) })},
+	{"upload_errors", numeric(func(r *QualityResult) float64 { return float64(r.UploadErrors) })},
+	{"latency_errors", numeric(func(r *QualityResult) float64 { return float64(r.LatencyErrors) })},
+	{"latency_p50_ms", numeric(func(r *QualityResult) float64 { return r.LatencyP50Ms })},
+	{"latency_p95_ms", numeric(func(r *QualityResult) float64 { return r.LatencyP95Ms })},
+	{"latency_p99_ms", numeric(func(r *QualityResult) float64 { return r.LatencyP99Ms })},
+	{"download_bytes", numeric(func(r *QualityResult) float64 { return float64(r.DownloadBytes) })},
+	{"upload_bytes", numeric(func(r *QualityResult) float64 { return float64(r.UploadBytes) })},
+	{"downlink_average", numeric(func(r *QualityResult) float64 { return r.DownlinkAverage })},
+	{"estimated_loss_percent", numeric(func(r *QualityResult) float64 { return r.EstimatedLossPercent })},
+	{"loaded_latency_p95_ms", numeric(func(r *QualityResult) float64 { return r.LoadedLatencyP95Ms })},
+	{"loaded_ttfb_ms", numeric(func(r *QualityResult) float64 { return r.LoadedTTFBMs })},
+	{"bufferbloat_ratio", numeric(func(r *QualityResult) float64 { return r.BufferbloatRatio })},
+	{"stabilized", numeric(func(r *QualityResult) float64 { return boolValue(r.Stabilized) })},
+	{"upload_loaded_latency_ms", numeric(func(r *QualityResult) float64 { return r.UploadLoadedLatencyMs })},
+	{"shaping_suspected", numeric(func(r *QualityResult) float64 { return boolValue(r.ShapingSuspected) })},
+	{"connections", numeric(func(r *QualityResult) float64 { return float64(r.Connections) })},
+	{"warm_latency_ms", numeric(func(r *QualityResult) float64 { return r.WarmLatencyMs })},
+	{"caveats", func(r *QualityResult) string { return strings.Join(r.Caveats, ";") }},
+}
+
+// boolValue returns 1 for true and 0 for false, for numeric outputs
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// AppendCSV appends the result as a row to the CSV file at path, writing a
+// header first when the file does not exist yet. The first row is published
+// atomically together with the header, so concurrent runs never leave a
+// file with a missing or duplicated header.
+func AppendCSV(path string, r *QualityResult) error {
+	if r == nil {
+		return fmt.Errorf("no result to export")
+	}
+
+	row, err := encodeCSV(csvRow(r))
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		created, err := createCSV(path, row)
+		if err != nil {
+			return err
+		}
+		if created {
+			return nil
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	// A single write keeps rows from concurrent runs from interleaving
+	if _, err := f.Write(row); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to append to CSV file: %w", err)
+	}
+	return f.Close()
+}
+
+// createCSV writes the header and first row to a temporary file and links
+// it into place, reporting false if another process created path first
+func createCSV(path string, row []byte) (bool, error) {
+	header, err := encodeCSV(csvHeader())
+	if err != nil {
+		return false, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".networkquality-*.csv")
+	if err != nil {
+		return false, fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(header, row...))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to write CSV file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return false, fmt.Errorf("failed to write CSV file: %w", err)
+	}
+
+	// Unlike rename, link fails when path already exists
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	return true, nil
+}
+
+// csvHeader returns the header record
+func csvHeader() []string {
+	header := []string{"timestamp"}
+	for _, c := range csvColumns {
+		header = append(header, c.name)
+	}
+	return header
+}
+
+// csvRow returns the record for a single result
+func csvRow(r *QualityResult) []string {
+	timestamp := r.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	row := []string{timestamp.UTC().Format(time.RFC3339)}
+	for _, c := range csvColumns {
+		row = append(row, c.value(r))
+	}
+	return row
+}
+
+// encodeCSV encodes a single record
+func encodeCSV(record []string) ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if err := w.Write(record); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	return b.Bytes(), w.Error()
+}
@@ -0,0 +1,91 @@
+package network
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minDownloadRequestTime is how long each download request should last at
+// least; faster responses have their requested size scaled up
+const minDownloadRequestTime = 500 * time.Millisecond
+
+// maxDownloadRequestBytes caps the size requested from servers that honor
+// the bytes query parameter
+const maxDownloadRequestBytes = 1 << 30 // 1GB
+
+// downloadSizer adapts the bytes query parameter of a download URL, as used
+// by the Cloudflare speed test, so each request lasts a reasonable slice of
+// the test window. URLs without the parameter are left unchanged. Each
+// worker owns its own sizer.
+type downloadSizer struct {
+	raw   string
+	u     *url.URL
+	bytes int64 // 0 when the URL has no usable bytes parameter
+}
+
+func newDownloadSizer(raw string) *downloadSizer {
+	d := &downloadSizer{raw: raw}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return d
+	}
+	n, err := strconv.ParseInt(u.Query().Get("bytes"), 10, 64)
+	if err != nil || n <= 0 {
+		return d
+	}
+	d.u = u
+	d.bytes = n
+	return d
+}
+
+// url returns the URL for the next request
+func (d *downloadSizer) url() string {
+	if d.bytes == 0 {
+		return d.raw
+	}
+	u := *d.u
+	q := u.Query()
+	q.Set("bytes", strconv.FormatInt(d.bytes, 10))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// adapt grows the requested size when a complete response of received bytes
+// took less than minDownloadRequestTime
+func (d *downloadSizer) adapt(received int64, elapsed time.Duration) {
+	if d.bytes == 0 || received < d.bytes || elapsed >= minDownloadRequestTime {
+		return
+	}
+	d.bytes *= 2
+	if d.bytes > maxDownloadRequestBytes {
+		d.bytes = maxDownloadRequestBytes
+	}
+}
+
+// serverLocation returns the data center that served a response, from CDN
+// headers such as Cloudflare's cf-meta-colo or the colo suffix of cf-ray,
+// or "" when none are present
+func serverLocation(h http.Header) string {
+	if colo := h.Get("Cf-Meta-Colo"); colo != "" {
+		if city := h.Get("Cf-Meta-City"); city != "" {
+			return colo + " (" + city + ")"
+		}
+		return colo
+	}
+	// cf-ray looks like "8a1b2c3d4e5f6a7b-LHR"
+	if ray := h.Get("Cf-Ray"); ray != "" {
+		if _, colo, ok := strings.Cut(ray, "-"); ok && colo != "" {
+			return colo
+		}
+	}
+	// Fastly's x-served-by looks like "cache-lhr7321-LHR"
+	if servedBy := h.Get("X-Served-By"); servedBy != "" {
+		if i := strings.LastIndex(servedBy, "-"); i >= 0 && i < len(servedBy)-1 {
+			return servedBy[i+1:]
+		}
+	}
+	return ""
+}
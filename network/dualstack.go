@@ -0,0 +1,53 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// RunDualStack runs the test twice, forcing IPv4 and then IPv6, so the two
+// address families can be compared side by side. When one family fails,
+// for instance because the host has no IPv6 route, the other result is
+// returned with nil for the failed one and an error wrapping
+// ErrFamilyUnreachable that says which family failed and why; only when
+// both fail are both results nil. If ctx ends mid-run, whatever completed
+// is returned with an error wrapping ErrPartial.
+// HTTP3, DialContext, and a shared Transport are rejected, since they do
+// not honor IPVersion.
+func RunDualStack(ctx context.Context, config *TestConfig) (v4 *QualityResult, v6 *QualityResult, err error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	switch {
+	case config.HTTP3:
+		return nil, nil, fmt.Errorf("dual-stack mode cannot force IP versions over HTTP/3")
+	case config.DialContext != nil:
+		return nil, nil, fmt.Errorf("dual-stack mode cannot force IP versions with DialContext")
+	case config.Transport != nil:
+		return nil, nil, fmt.Errorf("dual-stack mode cannot force IP versions on a shared Transport")
+	}
+
+	run := func(version string) (*QualityResult, error) {
+		familyConfig := config.Clone()
+		familyConfig.IPVersion = version
+		result, err := RunQualityTest(ctx, familyConfig)
+		if err != nil {
+			err = fmt.Errorf("IPv%s: %w", version, err)
+		}
+		return result, err
+	}
+
+	v4, err4 := run(IPv4)
+	v6, err6 := run(IPv6)
+
+	err = errors.Join(err4, err6)
+	switch {
+	case err == nil, v4 == nil && v6 == nil, errors.Is(err, ErrPartial):
+	case ctx.Err() != nil:
+		err = fmt.Errorf("%w: %w", ErrPartial, err)
+	default:
+		err = fmt.Errorf("%w: %w", ErrFamilyUnreachable, err)
+	}
+	return v4, v6, err
+}
@@ -0,0 +1,157 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envVars lists the environment variables read by ApplyEnv, each setting
+// the config file field with the same meaning. NQ_DURATION is the total
+// test time, like the -d and -duration flags.
+var envVars = []struct {
+	name string
+	set  func(f *configFile, value string) error
+}{
+	{"NQ_DURATION", envField(func(f *configFile) **duration { return &f.TotalBudget }, parseEnvDuration)},
+	{"NQ_TEST_DURATION", envField(func(f *configFile) **duration { return &f.TestDuration }, parseEnvDuration)},
+	{"NQ_CONNECTIONS", envField(func(f *configFile) **int { return &f.NumConnections }, parseEnvInt)},
+	{"NQ_DOWN_URL", envList(func(f *configFile) *[]string { return &f.TestServers })},
+	{"NQ_UP_URL", envList(func(f *configFile) *[]string { return &f.UploadServers })},
+	{"NQ_LATENCY_URL", envField(func(f *configFile) **string { return &f.LatencyServer }, parseEnvURL)},
+	{"NQ_AUTO_CONNECTIONS", envField(func(f *configFile) **bool { return &f.AutoConnections }, parseEnvBool)},
+	{"NQ_UPLOAD_CHUNK_SIZE", envField(func(f *configFile) **int { return &f.UploadChunkSize }, parseEnvInt)},
+	{"NQ_LATENCY_SAMPLES", envField(func(f *configFile) **int { return &f.LatencySamples }, parseEnvInt)},
+	{"NQ_WARMUP", envField(func(f *configFile) **duration { return &f.WarmupDuration }, parseEnvDuration)},
+	{"NQ_REQUEST_TIMEOUT", envField(func(f *configFile) **duration { return &f.RequestTimeout }, parseEnvDuration)},
+	{"NQ_LATENCY_TIMEOUT", envField(func(f *configFile) **duration { return &f.LatencyTimeout }, parseEnvDuration)},
+	{"NQ_IP_VERSION", envField(func(f *configFile) **string { return &f.IPVersion }, parseEnvString)},
+	{"NQ_BIDIRECTIONAL", envField(func(f *configFile) **bool { return &f.Bidirectional }, parseEnvBool)},
+	{"NQ_SKIP_DOWNLOAD", envField(func(f *configFile) **bool { return &f.SkipDownload }, parseEnvBool)},
+	{"NQ_SKIP_UPLOAD", envField(func(f *configFile) **bool { return &f.SkipUpload }, parseEnvBool)},
+	{"NQ_USER_AGENT", envField(func(f *configFile) **string { return &f.UserAgent }, parseEnvString)},
+	{"NQ_PROXY", envField(func(f *configFile) **string { return &f.ProxyURL }, parseEnvString)},
+	{"NQ_SOCKS5", envField(func(f *configFile) **string { return &f.SOCKS5 }, parseEnvString)},
+	{"NQ_SOCKS5_REMOTE_DNS", envField(func(f *configFile) **bool { return &f.SOCKS5RemoteDNS }, parseEnvBool)},
+	{"NQ_CAPTIVE_PORTAL_URL", envField(func(f *configFile) **string { return &f.CaptivePortal }, parseEnvString)},
+	{"NQ_HTTP3", envField(func(f *configFile) **bool { return &f.HTTP3 }, parseEnvBool)},
+	{"NQ_FOLLOW_REDIRECTS", envField(func(f *configFile) **bool { return &f.FollowRedirects }, parseEnvBool)},
+	{"NQ_MAX_BYTES", envField(func(f *configFile) **int64 { return &f.MaxBytes }, parseEnvInt64)},
+	{"NQ_MAX_MBPS", envField(func(f *configFile) **float64 { return &f.MaxMbps }, parseEnvFloat)},
+}
+
+// ConfigFromEnv returns a DefaultConfig with the NQ_* environment variables
+// applied, such as NQ_DURATION=30s, NQ_CONNECTIONS=8, or
+// NQ_DOWN_URL=https://a/down,https://b/down
+func ConfigFromEnv() (*TestConfig, error) {
+	config := DefaultConfig()
+	if err := ApplyEnv(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ApplyEnv sets the fields of config named by NQ_* environment variables,
+// leaving the rest untouched, e.g. to layer the environment over a config
+// file. Empty variables count as unset. Server lists are comma separated.
+// On an invalid value config is left unchanged.
+func ApplyEnv(config *TestConfig) error {
+	var file configFile
+	for _, v := range envVars {
+		value := strings.TrimSpace(os.Getenv(v.name))
+		if value == "" {
+			continue
+		}
+		if err := v.set(&file, value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", v.name, value, err)
+		}
+	}
+	file.apply(config)
+	return nil
+}
+
+// envField returns a setter parsing an environment variable into the
+// config file field returned by field
+func envField[T any](field func(*configFile) **T, parse func(string) (T, error)) func(*configFile, string) error {
+	return func(f *configFile, value string) error {
+		parsed, err := parse(value)
+		if err != nil {
+			return err
+		}
+		*field(f) = &parsed
+		return nil
+	}
+}
+
+// envList returns a setter splitting a comma separated environment
+// variable of server URLs into the config file list returned by field
+func envList(field func(*configFile) *[]string) func(*configFile, string) error {
+	return func(f *configFile, value string) error {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			if err := checkServerURL(item); err != nil {
+				return fmt.Errorf("%q: %w", item, err)
+			}
+			list = append(list, item)
+		}
+		*field(f) = list
+		return nil
+	}
+}
+
+func parseEnvString(value string) (string, error) {
+	return value, nil
+}
+
+func parseEnvURL(value string) (string, error) {
+	if err := checkServerURL(value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func parseEnvDuration(value string) (duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.New("must be a duration such as 30s or 2m")
+	}
+	return duration(d), nil
+}
+
+func parseEnvInt(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("must be a whole number")
+	}
+	return n, nil
+}
+
+func parseEnvInt64(value string) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.New("must be a whole number")
+	}
+	return n, nil
+}
+
+func parseEnvFloat(value string) (float64, error) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, errors.New("must be a number")
+	}
+	return n, nil
+}
+
+func parseEnvBool(value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("must be true or false")
+	}
+	return b, nil
+}
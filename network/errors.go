@@ -0,0 +1,36 @@
+package network
+
+import "errors"
+
+// Errors returned by the package, wrapped with details where useful so
+// callers can test for them with errors.Is
+var (
+	// ErrInvalidDuration is returned for a TestDuration that is not positive,
+	// or a negative TotalBudget
+	ErrInvalidDuration = errors.New("test duration must be positive")
+
+	// ErrNoDownloadServers is returned when TestServers is empty
+	ErrNoDownloadServers = errors.New("no download test servers configured")
+
+	// ErrNoUploadServers is returned when UploadServers is empty
+	ErrNoUploadServers = errors.New("no upload servers configured")
+
+	// ErrAllLatencyFailed is returned when every idle latency probe failed
+	ErrAllLatencyFailed = errors.New("all latency tests failed")
+
+	// ErrCaptivePortal is returned when the pre-flight check finds the
+	// network behind a captive portal, such as a hotel or airport login page
+	ErrCaptivePortal = errors.New("captive portal detected")
+
+	// ErrHTTP3Unavailable is returned when TestConfig.HTTP3 is set but a
+	// server cannot be reached over HTTP/3
+	ErrHTTP3Unavailable = errors.New("HTTP/3 unavailable")
+
+	// ErrFamilyUnreachable is returned by RunDualStack along with the
+	// result of one address family when the other could not be measured
+	ErrFamilyUnreachable = errors.New("address family unreachable")
+
+	// ErrPartial is returned with a partially filled result when the test
+	// was cancelled or timed out after at least one phase completed
+	ErrPartial = errors.New("test interrupted, results are partial")
+)
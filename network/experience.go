@@ -0,0 +1,117 @@
+package network
+
+// ExperienceScores rates how well the connection suits common activities,
+// each with one of the Score labels, in the spirit of Cloudflare's
+// Aggregated Internet Measurement (AIM) scores
+type ExperienceScores struct {
+	Streaming string `json:"streaming"`  // video streaming
+	Gaming    string `json:"gaming"`     // online gaming
+	VideoChat string `json:"video_chat"` // video calls and other real-time communication
+}
+
+// ExperienceUnknown is the ExperienceScores label of an activity that
+// depends on a throughput phase skipped with SkipDownload or SkipUpload
+const ExperienceUnknown = "Unknown"
+
+// experienceRequirement grades one metric against the limits needed for
+// ScoreExcellent, ScoreGood, and ScoreFair; anything worse is ScorePoor
+type experienceRequirement struct {
+	value          func(r *QualityResult) float64
+	limits         [3]float64
+	higherIsBetter bool
+	phase          string // throughput phase the value comes from, if any
+}
+
+func minDownlink(excellent, good, fair float64) experienceRequirement {
+	return experienceRequirement{func(r *QualityResult) float64 { return r.DownlinkCapacity }, [3]float64{excellent, good, fair}, true, PhaseDownload}
+}
+
+func minUplink(excellent, good, fair float64) experienceRequirement {
+	return experienceRequirement{func(r *QualityResult) float64 { return r.UplinkCapacity }, [3]float64{excellent, good, fair}, true, PhaseUpload}
+}
+
+func maxIdleLatency(excellent, good, fair float64) experienceRequirement {
+	return experienceRequirement{func(r *QualityResult) float64 { return r.IdleLatency }, [3]float64{excellent, good, fair}, false, ""}
+}
+
+func maxLoadedLatency(excellent, good, fair float64) experienceRequirement {
+	return experienceRequirement{func(r *QualityResult) float64 { return r.ResponsivenessMs }, [3]float64{excellent, good, fair}, false, ""}
+}
+
+func maxJitter(excellent, good, fair float64) experienceRequirement {
+	return experienceRequirement{func(r *QualityResult) float64 { return r.JitterMs }, [3]float64{excellent, good, fair}, false, ""}
+}
+
+func maxLoss(excellent, good, fair float64) experienceRequirement {
+	return experienceRequirement{func(r *QualityResult) float64 { return r.EstimatedLossPercent }, [3]float64{excellent, good, fair}, false, ""}
+}
+
+// Requirements for each activity, in Mbps, milliseconds, and percent loss.
+// Streaming needs bandwidth and tolerates latency thanks to buffering;
+// gaming needs low, steady latency but little bandwidth; video chat needs
+// both upload bandwidth and steady latency.
+var (
+	streamingRequirements = []experienceRequirement{
+		minDownlink(25, 15, 5),
+		maxLoadedLatency(200, 500, 1000),
+		maxLoss(1, 3, 5),
+	}
+	gamingRequirements = []experienceRequirement{
+		minDownlink(15, 5, 3),
+		maxIdleLatency(20, 50, 100),
+		maxLoadedLatency(50, 100, 200),
+		maxJitter(5, 15, 30),
+		maxLoss(0.5, 1, 3),
+	}
+	videoChatRequirements = []experienceRequirement{
+		minDownlink(10, 5, 2),
+		minUplink(5, 3, 1.5),
+		maxIdleLatency(50, 100, 150),
+		maxJitter(10, 30, 50),
+		maxLoss(1, 3, 5),
+	}
+)
+
+// ExperienceScores grades the result for streaming, gaming, and video chat.
+// Each activity gets the worst grade among the metrics it depends on, or
+// ExperienceUnknown when one of them comes from a skipped phase.
+func (r *QualityResult) ExperienceScores() ExperienceScores {
+	return ExperienceScores{
+		Streaming: r.gradeExperience(streamingRequirements),
+		Gaming:    r.gradeExperience(gamingRequirements),
+		VideoChat: r.gradeExperience(videoChatRequirements),
+	}
+}
+
+// gradeExperience returns the worst label any requirement earns
+func (r *QualityResult) gradeExperience(requirements []experienceRequirement) string {
+	labels := []string{ScoreExcellent, ScoreGood, ScoreFair, ScorePoor}
+	worst := 0
+	for _, req := range requirements {
+		if r.skipped(req.phase) {
+			return ExperienceUnknown
+		}
+		v := req.value(r)
+		grade := len(req.limits)
+		for i, limit := range req.limits {
+			if (req.higherIsBetter && v >= limit) || (!req.higherIsBetter && v <= limit) {
+				grade = i
+				break
+			}
+		}
+		worst = max(worst, grade)
+	}
+	return labels[worst]
+}
+
+// skipped reports whether phase was left out of the run with SkipDownload
+// or SkipUpload
+func (r *QualityResult) skipped(phase string) bool {
+	switch phase {
+	case PhaseDownload:
+		return r.Config.SkipDownload
+	case PhaseUpload:
+		return r.Config.SkipUpload
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package network
+
+import "testing"
+
+func TestExperienceScoresSkippedPhase(t *testing.T) {
+	r := &QualityResult{
+		DownlinkCapacity: 100,
+		IdleLatency:      5,
+		ResponsivenessMs: 20,
+		JitterMs:         1,
+		Config:           ConfigSnapshot{SkipUpload: true},
+	}
+	want := ExperienceScores{Streaming: ScoreExcellent, Gaming: ScoreExcellent, VideoChat: ExperienceUnknown}
+	if got := r.ExperienceScores(); got != want {
+		t.Errorf("with upload skipped got %+v, want %+v", got, want)
+	}
+
+	r.Config = ConfigSnapshot{SkipDownload: true}
+	r.DownlinkCapacity = 0
+	r.UplinkCapacity = 50
+	want = ExperienceScores{Streaming: ExperienceUnknown, Gaming: ExperienceUnknown, VideoChat: ExperienceUnknown}
+	if got := r.ExperienceScores(); got != want {
+		t.Errorf("with download skipped got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,171 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultHistoryCapacity is the number of results NewHistory keeps when
+// given a capacity below 1
+const DefaultHistoryCapacity = 100
+
+// History keeps the most recent results in a ring buffer, e.g. for a
+// monitor to show trends without an external database. The oldest result
+// is dropped once the capacity is reached. It is safe for concurrent use.
+type History struct {
+	mu       sync.Mutex
+	results  []*QualityResult
+	next     int // index the next result is written to once full
+	capacity int
+}
+
+// MetricRange summarizes a metric over the results in a History
+type MetricRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+// HistorySummary is the rolling summary of the results in a History
+type HistorySummary struct {
+	Count         int         `json:"count"`
+	First         time.Time   `json:"first"` // timestamp of the oldest result
+	Last          time.Time   `json:"last"`  // timestamp of the newest result
+	Downlink      MetricRange `json:"downlink_capacity"`
+	Uplink        MetricRange `json:"uplink_capacity"`
+	IdleLatency   MetricRange `json:"idle_latency"`
+	Jitter        MetricRange `json:"jitter_ms"`
+	LoadedLatency MetricRange `json:"responsiveness_ms"`
+	RPM           MetricRange `json:"rpm"`
+}
+
+// NewHistory returns an empty History holding up to capacity results, or
+// DefaultHistoryCapacity when capacity is below 1
+func NewHistory(capacity int) *History {
+	if capacity < 1 {
+		capacity = DefaultHistoryCapacity
+	}
+	return &History{capacity: capacity}
+}
+
+// Add records a result, dropping the oldest one when the History is full.
+// Nil results are ignored.
+func (h *History) Add(r *QualityResult) {
+	if r == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.results) < h.capacity {
+		h.results = append(h.results, r)
+		return
+	}
+	h.results[h.next] = r
+	h.next = (h.next + 1) % h.capacity
+}
+
+// Len returns the number of results held
+func (h *History) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.results)
+}
+
+// Results returns the results held, oldest first
+func (h *History) Results() []*QualityResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ordered := make([]*QualityResult, 0, len(h.results))
+	ordered = append(ordered, h.results[h.next:]...)
+	return append(ordered, h.results[:h.next]...)
+}
+
+// Summary returns the min, max, and average of the main metrics over the
+// results held. The zero summary is returned for an empty History.
+func (h *History) Summary() HistorySummary {
+	results := h.Results()
+	if len(results) == 0 {
+		return HistorySummary{}
+	}
+
+	metric := func(value func(r *QualityResult) float64) MetricRange {
+		m := MetricRange{Min: value(results[0]), Max: value(results[0])}
+		var sum float64
+		for _, r := range results {
+			v := value(r)
+			m.Min = min(m.Min, v)
+			m.Max = max(m.Max, v)
+			sum += v
+		}
+		m.Avg = round3(sum / float64(len(results)))
+		return m
+	}
+	return HistorySummary{
+		Count:         len(results),
+		First:         results[0].Timestamp,
+		Last:          results[len(results)-1].Timestamp,
+		Downlink:      metric(func(r *QualityResult) float64 { return r.DownlinkCapacity }),
+		Uplink:        metric(func(r *QualityResult) float64 { return r.UplinkCapacity }),
+		IdleLatency:   metric(func(r *QualityResult) float64 { return r.IdleLatency }),
+		Jitter:        metric(func(r *QualityResult) float64 { return r.JitterMs }),
+		LoadedLatency: metric(func(r *QualityResult) float64 { return r.ResponsivenessMs }),
+		RPM:           metric(func(r *QualityResult) float64 { return float64(r.RPM) }),
+	}
+}
+
+// Save writes the results held to path as a JSON array, oldest first,
+// replacing the file atomically so a crash never leaves it truncated
+func (h *History) Save(path string) error {
+	data, err := json.Marshal(h.Results())
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".history-*")
+	if err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	return nil
+}
+
+// LoadHistory returns a History holding up to capacity results, filled
+// from a file written by Save. A missing file gives an empty History; when
+// the file holds more than capacity results, the newest are kept.
+func LoadHistory(path string, capacity int) (*History, error) {
+	h := NewHistory(capacity)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	var results []*QualityResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("invalid history file %s: %w", path, err)
+	}
+	for _, r := range results {
+		h.Add(r)
+	}
+	return h, nil
+}
@@ -0,0 +1,52 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// checkHTTP3URLs rejects servers that cannot be reached over HTTP/3, which
+// only runs over TLS
+func checkHTTP3URLs(config *TestConfig) error {
+	urls := []string{config.LatencyURL()}
+	if !config.SkipDownload {
+		urls = append(urls, config.TestServers...)
+	}
+	if !config.SkipUpload {
+		urls = append(urls, config.UploadServers...)
+	}
+	for _, u := range urls {
+		if !strings.HasPrefix(strings.ToLower(u), "https://") {
+			return fmt.Errorf("%w: %s is not an https URL", ErrHTTP3Unavailable, u)
+		}
+	}
+	return nil
+}
+
+// checkHTTP3 sends one request to the latency server over HTTP/3, so a
+// server without it fails the run up front with ErrHTTP3Unavailable
+// instead of as a string of failed probes
+func checkHTTP3(ctx context.Context, config *TestConfig) error {
+	if !config.HTTP3 {
+		return nil
+	}
+
+	client := newClient(config, config.latencyTimeout())
+	req, err := http.NewRequestWithContext(ctx, "GET", config.LatencyURL(), nil)
+	if err != nil {
+		return fmt.Errorf("invalid latency URL: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s: %w", ErrHTTP3Unavailable, config.LatencyURL(), err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil
+}
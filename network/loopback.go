@@ -0,0 +1,13 @@
+package network
+
+import "net/http/httptest"
+
+// NewLoopbackConfig starts a local HTTP server that serves downloads,
+// accepts uploads, and answers latency probes, and returns a DefaultConfig
+// pointed at it. Runs against it need no network access, which makes it
+// useful for checking the measurement pipeline: throughput should be high
+// and latency near zero. Call the returned function to stop the server.
+func NewLoopbackConfig() (*TestConfig, func()) {
+	server := httptest.NewServer(NewServerHandler())
+	return ServerConfig(server.URL), server.Close
+}
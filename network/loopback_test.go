@@ -0,0 +1,59 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestConfig returns a loopback config with short phases, so tests run
+// the real measurement code in a few seconds without network access
+func newTestConfig(t *testing.T) *TestConfig {
+	t.Helper()
+	config, stop := NewLoopbackConfig()
+	t.Cleanup(stop)
+	shortenTestConfig(config)
+	return config
+}
+
+// shortenTestConfig cuts every phase of config down to about a second
+func shortenTestConfig(config *TestConfig) {
+	config.TestDuration = time.Second
+	config.WarmupDuration = 0
+	config.LatencySamples = 5
+	config.LossProbes = 0
+}
+
+// prepareTestConfig runs prepareConfig on config, for tests that call a
+// single phase directly
+func prepareTestConfig(t *testing.T, config *TestConfig) *TestConfig {
+	t.Helper()
+	prepared, cleanup, err := prepareConfig(config)
+	if err != nil {
+		t.Fatalf("prepareConfig: %v", err)
+	}
+	t.Cleanup(cleanup)
+	return prepared
+}
+
+func TestLoopbackConfig(t *testing.T) {
+	config := newTestConfig(t)
+	result, err := RunQualityTest(context.Background(), config)
+	if err != nil {
+		t.Fatalf("RunQualityTest: %v", err)
+	}
+
+	if result.DownlinkCapacity <= 0 || result.UplinkCapacity <= 0 {
+		t.Errorf("got %v Mbps down and %v Mbps up, want both positive",
+			result.DownlinkCapacity, result.UplinkCapacity)
+	}
+	// Generous for a loaded CI machine, yet far below any real network
+	const maxLatencyMs = 50
+	if result.IdleLatency < 0 || result.IdleLatency > maxLatencyMs {
+		t.Errorf("got idle latency %v ms, want it near zero", result.IdleLatency)
+	}
+	if result.DownloadErrors+result.UploadErrors+result.LatencyErrors > 0 {
+		t.Errorf("got %d download, %d upload, and %d latency errors, want none",
+			result.DownloadErrors, result.UploadErrors, result.LatencyErrors)
+	}
+}
@@ -0,0 +1,86 @@
+package network
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults for the loss probes sent during the download phase
+const (
+	defaultLossProbes       = 50
+	defaultLossProbeTimeout = 2 * time.Second
+)
+
+// lossStats counts the loss probes sent while the link was saturated
+type lossStats struct {
+	probes   int
+	failures int
+}
+
+// percent returns the share of probes that failed as a percentage, or 0
+// when none were sent
+func (s lossStats) percent() float64 {
+	if s.probes == 0 {
+		return 0
+	}
+	return round3(float64(s.failures) / float64(s.probes) * 100)
+}
+
+// measureLoss spreads LossProbes independent latency probes evenly from
+// LoadProbeDelay after start until deadline and counts those that time out
+// or fail. Unlike loaded latency probes they do not wait for each other, so
+// a stalled probe does not hide the ones after it. Probes cut short by ctx
+// are not counted.
+func measureLoss(ctx context.Context, config *TestConfig, testURL string, start, deadline time.Time) lossStats {
+	if config.LossProbes <= 0 {
+		return lossStats{}
+	}
+
+	delay := max(config.LoadProbeDelay, 0)
+	window := deadline.Sub(start)
+	if delay > window/2 {
+		delay = window / 2
+	}
+	spacing := (window - delay) / time.Duration(config.LossProbes)
+
+	client := newClient(config, config.lossProbeTimeout())
+
+	var probes, failures atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < config.LossProbes; i++ {
+		if sleepContext(ctx, time.Until(start.Add(delay+time.Duration(i)*spacing))) != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := probeLatency(ctx, client, testURL, nil)
+			if ctx.Err() != nil {
+				return
+			}
+			probes.Add(1)
+			if err != nil {
+				config.debug("loss probe failed", "server", testURL, "error", err)
+				failures.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := lossStats{probes: int(probes.Load()), failures: int(failures.Load())}
+	config.debug("loss probes finished", "server", testURL, "probes", stats.probes,
+		"failures", stats.failures, "loss_percent", stats.percent())
+	return stats
+}
+
+// lossProbeTimeout returns the timeout after which a loss probe counts as
+// lost
+func (c *TestConfig) lossProbeTimeout() time.Duration {
+	if c.LossProbeTimeout > 0 {
+		return c.LossProbeTimeout
+	}
+	return defaultLossProbeTimeout
+}
@@ -0,0 +1,87 @@
+package network
+
+import (
+	"context"
+	"time"
+)
+
+// Measurer measures throughput in one direction, letting callers swap in
+// their own saturation strategy. Measure should run for about the phase
+// window (WarmupDuration plus TestDuration for downloads, plus
+// UploadDuration for uploads) and return the throughput in Mbps.
+type Measurer interface {
+	Measure(ctx context.Context, config *TestConfig) (float64, error)
+}
+
+// DefaultMeasurer is the built-in measurement: NumConnections parallel
+// requests spread across the servers, repeated until the window ends.
+// Phase selects PhaseDownload (the default) or PhaseUpload when called
+// directly. Set as a TestConfig measurer it always measures that field's
+// phase and keeps the full result, just like a nil measurer.
+type DefaultMeasurer struct {
+	Phase string
+}
+
+// Measure implements Measurer
+func (m DefaultMeasurer) Measure(ctx context.Context, config *TestConfig) (float64, error) {
+	if m.Phase == PhaseUpload {
+		stats, err := measureUploadSpeed(ctx, config)
+		return stats.mbps, err
+	}
+	stats, _, err := measureDownloadSpeed(ctx, config, config.LatencyURL())
+	return stats.mbps, err
+}
+
+// runDownload measures the download phase with the configured
+// DownloadMeasurer, falling back to the built-in measurement
+func runDownload(ctx context.Context, config *TestConfig, latencyURL string) (transferStats, loadedLatency, error) {
+	if isDefaultMeasurer(config.DownloadMeasurer) {
+		if config.BurstMode {
+			return measureDownloadBursts(ctx, config, latencyURL)
+		}
+		return measureDownloadSpeed(ctx, config, latencyURL)
+	}
+
+	start := time.Now()
+	window := config.warmup() + config.TestDuration
+	deadline := start.Add(window)
+
+	// Probing stops as soon as the measurer returns, even if early
+	probeCtx, stopProbes := context.WithCancel(ctx)
+	defer stopProbes()
+	waitProbes := probeUnderLoad(probeCtx, config, latencyURL, start, deadline)
+
+	stopProgress := trackProgress(config, PhaseDownload, start, window)
+	mbps, err := config.DownloadMeasurer.Measure(ctx, config)
+	stopProgress()
+	stats := transferStats{mbps: round3(mbps), window: time.Since(start)}
+
+	stopProbes()
+	return stats, waitProbes(), err
+}
+
+// runUpload measures the upload phase with the configured UploadMeasurer,
+// falling back to the built-in measurement
+func runUpload(ctx context.Context, config *TestConfig) (transferStats, error) {
+	if isDefaultMeasurer(config.UploadMeasurer) {
+		if len(config.UploadChunkSizes) > 0 {
+			return measureUploadSizes(ctx, config)
+		}
+		return measureUploadSpeed(ctx, config)
+	}
+
+	start := time.Now()
+	stopProgress := trackProgress(config, PhaseUpload, start, config.warmup()+config.UploadDuration())
+	mbps, err := config.UploadMeasurer.Measure(ctx, config)
+	stopProgress()
+	return transferStats{mbps: round3(mbps), window: time.Since(start)}, err
+}
+
+// isDefaultMeasurer reports whether m selects the built-in measurement
+func isDefaultMeasurer(m Measurer) bool {
+	switch m.(type) {
+	case nil, DefaultMeasurer, *DefaultMeasurer:
+		return true
+	}
+	return false
+}
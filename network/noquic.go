@@ -0,0 +1,14 @@
+//go:build nohttp3
+
+package network
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// newHTTP3Transport fails in nohttp3 builds, which leave out the QUIC
+// dependency
+func newHTTP3Transport(*TestConfig) (http.RoundTripper, func(), error) {
+	return nil, nil, fmt.Errorf("%w: built with the nohttp3 tag", ErrHTTP3Unavailable)
+}
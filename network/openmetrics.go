@@ -0,0 +1,56 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// responsivenessBuckets are the upper bounds of the loaded latency histogram,
+// matching the High and Medium responsiveness thresholds in milliseconds
+var responsivenessBuckets = []float64{200, 1000}
+
+// WriteOpenMetrics writes the result in the OpenMetrics text format. Besides
+// the gauges written by WritePrometheus, the p95 loaded latency is emitted
+// as a single-observation histogram bucketed by responsiveness, since
+// OpenMetrics only allows exemplars on histogram buckets and counters. When
+// traceID is non-empty it is attached to that observation as an exemplar,
+// linking the measurement to a trace.
+func WriteOpenMetrics(w io.Writer, r *QualityResult, traceID string) error {
+	if r == nil {
+		return fmt.Errorf("no result to export")
+	}
+
+	var b strings.Builder
+	writeMetrics(&b, r)
+
+	const name = "networkquality_responsiveness_ms"
+	value := r.LoadedLatencyP95Ms
+	fmt.Fprintf(&b, "# HELP %s 95th percentile of latency under load in milliseconds, bucketed by responsiveness.\n", name)
+	fmt.Fprintf(&b, "# TYPE %s histogram\n", name)
+
+	// The exemplar belongs to the first bucket holding the observation
+	exemplar := ""
+	if traceID != "" {
+		exemplar = fmt.Sprintf(" # {trace_id=\"%s\"} %g", escapeLabelValue(traceID), value)
+	}
+	for _, le := range responsivenessBuckets {
+		count := 0
+		if value <= le {
+			count = 1
+		}
+		fmt.Fprintf(&b, "%s_bucket{le=\"%g\"} %d", name, le, count)
+		if count == 1 {
+			b.WriteString(exemplar)
+			exemplar = ""
+		}
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "%s_bucket{le=\"+Inf\"} 1%s\n", name, exemplar)
+	fmt.Fprintf(&b, "%s_count 1\n", name)
+	fmt.Fprintf(&b, "%s_sum %g\n", name, value)
+	b.WriteString("# EOF\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,118 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// PingStats summarizes the latency probes sent by Ping. All latencies are in
+// milliseconds.
+type PingStats struct {
+	Sent   int
+	Failed int
+	Last   float64
+	Min    float64
+	Avg    float64
+	Max    float64
+	Jitter float64 // mean difference between consecutive samples
+}
+
+// Ping continuously probes the configured latency server every interval until
+// ctx is cancelled, reusing a single keep-alive connection. onSample, when
+// non-nil, is called with the running statistics after every probe. The final
+// statistics are returned once ctx is done.
+func Ping(ctx context.Context, config *TestConfig, interval time.Duration, onSample func(PingStats)) (PingStats, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	var stats PingStats
+	target := config.LatencyURL()
+	if target == "" {
+		return stats, fmt.Errorf("no latency test server configured")
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	if !validIPVersion(config.IPVersion) {
+		return stats, fmt.Errorf("invalid IP version %q: must be 4 or 6", config.IPVersion)
+	}
+
+	if config.HTTP3 && config.Transport == nil {
+		// QUIC already multiplexes every probe over one connection
+		transport, closeTransport, err := newHTTP3Transport(config)
+		if err != nil {
+			return stats, err
+		}
+		defer closeTransport()
+		pingConfig := *config
+		pingConfig.Transport = transport
+		config = &pingConfig
+	}
+	if config.Transport == nil {
+		// Probe over a single reused connection
+		transport, err := NewTransport(config)
+		if err != nil {
+			return stats, err
+		}
+		transport.MaxConnsPerHost = 1
+		pingConfig := *config
+		pingConfig.Transport = transport
+		config = &pingConfig
+	}
+	client := newClient(config, config.latencyTimeout())
+	defer client.CloseIdleConnections()
+
+	var total, totalDiff float64
+	received := 0
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		rtt, err := probeLatency(ctx, client, target, nil)
+		latency := durationMs(rtt)
+		if ctx.Err() != nil {
+			// The probe in flight was cut short by cancellation
+			break
+		}
+
+		stats.Sent++
+		if err != nil {
+			stats.Failed++
+		} else {
+			if received == 0 || latency < stats.Min {
+				stats.Min = latency
+			}
+			if latency > stats.Max {
+				stats.Max = latency
+			}
+			if received > 0 {
+				totalDiff += math.Abs(latency - stats.Last)
+				stats.Jitter = totalDiff / float64(received)
+			}
+			received++
+			total += latency
+			stats.Last = latency
+			stats.Avg = total / float64(received)
+		}
+
+		if onSample != nil {
+			onSample(stats)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+
+	if stats.Sent > 0 && received == 0 {
+		return stats, fmt.Errorf("all ping probes failed")
+	}
+
+	return stats, nil
+}
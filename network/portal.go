@@ -0,0 +1,60 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// defaultCaptivePortalURL must answer with an empty 204 on an open network
+const defaultCaptivePortalURL = "http://www.google.com/generate_204"
+
+// checkCaptivePortal requests the captive portal probe URL and returns
+// ErrCaptivePortal if the response is anything but an empty 204. Network
+// errors are ignored, since the test phases report those more precisely.
+func checkCaptivePortal(ctx context.Context, config *TestConfig) error {
+	if config.CaptivePortalURL == "" {
+		return nil
+	}
+
+	transport := config.Transport
+	if config.portalTransport != nil {
+		transport = config.portalTransport
+	}
+
+	// Headers such as Authorization are meant for the test servers only,
+	// so the probe bypasses newClient
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   config.latencyTimeout(),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", config.CaptivePortalURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid captive portal URL: %w", err)
+	}
+	req.Header.Set("User-Agent", config.userAgent())
+	resp, err := client.Do(req)
+	if err != nil {
+		config.debug("captive portal check skipped", "url", config.CaptivePortalURL, "error", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	// A byte is enough to tell an empty body from a login page
+	n, _ := io.CopyN(io.Discard, resp.Body, 1)
+	switch {
+	case resp.StatusCode != http.StatusNoContent:
+		if location := resp.Header.Get("Location"); location != "" {
+			return fmt.Errorf("%w: %s redirected to %s", ErrCaptivePortal, config.CaptivePortalURL, location)
+		}
+		return fmt.Errorf("%w: %s returned %s", ErrCaptivePortal, config.CaptivePortalURL, resp.Status)
+	case n > 0:
+		return fmt.Errorf("%w: %s returned a non-empty body", ErrCaptivePortal, config.CaptivePortalURL)
+	}
+	return nil
+}
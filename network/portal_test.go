@@ -0,0 +1,34 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCaptivePortalHTTP3 checks that HTTP3 runs still probe a plain HTTP
+// captive portal URL, which cannot be reached over QUIC
+func TestCaptivePortalHTTP3(t *testing.T) {
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://login.example/", http.StatusFound)
+	}))
+	defer portal.Close()
+
+	config := DefaultConfig()
+	config.HTTP3 = true
+	config.CaptivePortalURL = portal.URL
+	config, cleanup, err := prepareConfig(config)
+	if errors.Is(err, ErrHTTP3Unavailable) {
+		t.Skipf("HTTP/3 unavailable: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("prepareConfig: %v", err)
+	}
+	defer cleanup()
+
+	if err := checkCaptivePortal(context.Background(), config); !errors.Is(err, ErrCaptivePortal) {
+		t.Errorf("got error %v, want ErrCaptivePortal", err)
+	}
+}
@@ -0,0 +1,163 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// promSample is a single labeled value within a metric family
+type promSample struct {
+	labels []string // alternating label names and values
+	value  float64
+}
+
+// WritePrometheus writes the result in the Prometheus text exposition format.
+// Aggregate gauges are always emitted; labeled per-phase series are emitted
+// alongside them so dashboards can break results down further.
+func WritePrometheus(w io.Writer, r *QualityResult) error {
+	if r == nil {
+		return fmt.Errorf("no result to export")
+	}
+
+	var b strings.Builder
+	writeMetrics(&b, r)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMetrics writes every result gauge, in a form valid in both the
+// Prometheus and OpenMetrics text formats
+func writeMetrics(b *strings.Builder, r *QualityResult) {
+	writeGauge(b, "networkquality_downlink_mbps", "Downlink capacity in Mbps.",
+		promSample{value: r.DownlinkCapacity})
+	writeGauge(b, "networkquality_downlink_average_mbps", "Average download throughput over the whole window in Mbps.",
+		promSample{value: r.DownlinkAverage})
+	writeGauge(b, "networkquality_uplink_mbps", "Uplink capacity in Mbps.",
+		promSample{value: r.UplinkCapacity})
+	writeGauge(b, "networkquality_downlink_throughput_mbps", "Download throughput distribution over the sampled time series in Mbps.",
+		promSample{labels: []string{"quantile", "0.5"}, value: r.DownlinkP50},
+		promSample{labels: []string{"quantile", "0.9"}, value: r.DownlinkP90},
+		promSample{labels: []string{"quantile", "1"}, value: r.DownlinkPeak},
+	)
+	writeGauge(b, "networkquality_uplink_throughput_mbps", "Upload throughput distribution over the sampled time series in Mbps.",
+		promSample{labels: []string{"quantile", "0.9"}, value: r.UplinkP90},
+		promSample{labels: []string{"quantile", "1"}, value: r.UplinkPeak},
+	)
+	writeGauge(b, "networkquality_idle_latency_ms", "Idle latency in milliseconds.",
+		promSample{value: r.IdleLatency})
+	writeGauge(b, "networkquality_idle_latency_quantile_ms", "Idle latency distribution over all probes in milliseconds.",
+		promSample{labels: []string{"quantile", "0.5"}, value: r.LatencyP50Ms},
+		promSample{labels: []string{"quantile", "0.95"}, value: r.LatencyP95Ms},
+		promSample{labels: []string{"quantile", "0.99"}, value: r.LatencyP99Ms},
+	)
+	writeGauge(b, "networkquality_warm_latency_ms", "Idle latency over a reused keep-alive connection in milliseconds.",
+		promSample{value: r.WarmLatencyMs})
+	writeGauge(b, "networkquality_jitter_ms", "Idle latency jitter in milliseconds.",
+		promSample{value: r.JitterMs})
+	writeGauge(b, "networkquality_loaded_latency_ms", "Latency under load in milliseconds.",
+		promSample{labels: []string{"responsiveness", r.Responsiveness}, value: r.ResponsivenessMs})
+	writeGauge(b, "networkquality_loaded_latency_p95_ms", "95th percentile of latency under load in milliseconds, the basis of responsiveness.",
+		promSample{value: r.LoadedLatencyP95Ms})
+	writeGauge(b, "networkquality_upload_loaded_latency_ms", "Latency while only the upload saturates the link in milliseconds.",
+		promSample{value: r.UploadLoadedLatencyMs})
+	writeGauge(b, "networkquality_loaded_ttfb_ms", "Time to first byte of requests sent under load in milliseconds.",
+		promSample{value: r.LoadedTTFBMs})
+
+	writeGauge(b, "networkquality_latency_increase_ms", "Loaded minus idle latency in milliseconds.",
+		promSample{labels: []string{"grade", r.BufferbloatGrade}, value: r.LatencyIncreaseMs})
+	writeGauge(b, "networkquality_stabilized", "1 if throughput settled before each phase ended, 0 if it may be underestimated.",
+		promSample{value: boolValue(r.Stabilized)})
+	writeGauge(b, "networkquality_connections", "Parallel connections used by the throughput phases.",
+		promSample{value: float64(r.Connections)})
+	writeGauge(b, "networkquality_shaping_suspected", "1 if the download looked throttled by a traffic shaper, 0 otherwise.",
+		promSample{value: boolValue(r.ShapingSuspected)})
+	caveats := make([]promSample, 0, len(r.Caveats))
+	for _, c := range r.Caveats {
+		caveats = append(caveats, promSample{labels: []string{"caveat", c}, value: 1})
+	}
+	writeGauge(b, "networkquality_caveat", "1 for each reason the result may not be trustworthy.",
+		caveats...)
+	writeGauge(b, "networkquality_bufferbloat_ratio", "Loaded latency divided by idle latency, 0 if not measured.",
+		promSample{value: r.BufferbloatRatio})
+	writeGauge(b, "networkquality_rpm", "Round trips per minute under working conditions.",
+		promSample{value: float64(r.RPM)})
+	writeGauge(b, "networkquality_estimated_loss_percent", "Share of probes under load that timed out or failed.",
+		promSample{value: r.EstimatedLossPercent})
+
+	writeGauge(b, "networkquality_phase_window_seconds", "Configured and effective measurement window per phase.",
+		promSample{labels: []string{"phase", "download", "window", "configured"}, value: r.DownloadWindow.Configured.Seconds()},
+		promSample{labels: []string{"phase", "download", "window", "effective"}, value: r.DownloadWindow.Effective.Seconds()},
+		promSample{labels: []string{"phase", "upload", "window", "configured"}, value: r.UploadWindow.Configured.Seconds()},
+		promSample{labels: []string{"phase", "upload", "window", "effective"}, value: r.UploadWindow.Effective.Seconds()},
+	)
+
+	writeGauge(b, "networkquality_download_mbps", "Download throughput per server in Mbps.",
+		serverSamples(r.DownlinkPerServer)...)
+	writeGauge(b, "networkquality_upload_mbps", "Upload throughput per server in Mbps.",
+		serverSamples(r.UplinkPerServer)...)
+
+	chunkSizes := make([]int, 0, len(r.UplinkPerChunkSize))
+	for size := range r.UplinkPerChunkSize {
+		chunkSizes = append(chunkSizes, size)
+	}
+	slices.Sort(chunkSizes)
+	chunks := make([]promSample, 0, len(chunkSizes))
+	for _, size := range chunkSizes {
+		chunks = append(chunks, promSample{labels: []string{"chunk_bytes", strconv.Itoa(size)}, value: r.UplinkPerChunkSize[size]})
+	}
+	writeGauge(b, "networkquality_upload_chunk_mbps", "Upload throughput per request chunk size in Mbps.",
+		chunks...)
+
+	if !r.Timestamp.IsZero() {
+		writeGauge(b, "networkquality_last_run_timestamp_seconds", "Unix time the test completed.",
+			promSample{value: float64(r.Timestamp.Unix())})
+	}
+
+	connections := make([]promSample, 0, len(r.DownlinkPerConnection))
+	for i, mbps := range r.DownlinkPerConnection {
+		connections = append(connections, promSample{labels: []string{"connection", strconv.Itoa(i)}, value: mbps})
+	}
+	writeGauge(b, "networkquality_download_connection_mbps", "Download throughput per connection in Mbps.",
+		connections...)
+}
+
+// serverSamples labels per-server throughput with the server URL
+func serverSamples(servers []ServerThroughput) []promSample {
+	samples := make([]promSample, 0, len(servers))
+	for _, s := range servers {
+		samples = append(samples, promSample{labels: []string{"server", s.Server}, value: s.Mbps})
+	}
+	return samples
+}
+
+// writeGauge writes a gauge metric family with its HELP and TYPE lines
+func writeGauge(b *strings.Builder, name, help string, samples ...promSample) {
+	if len(samples) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s gauge\n", name)
+	for _, s := range samples {
+		b.WriteString(name)
+		if len(s.labels) > 0 {
+			b.WriteByte('{')
+			for i := 0; i+1 < len(s.labels); i += 2 {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				fmt.Fprintf(b, "%s=\"%s\"", s.labels[i], escapeLabelValue(s.labels[i+1]))
+			}
+			b.WriteByte('}')
+		}
+		fmt.Fprintf(b, " %g\n", s.value)
+	}
+}
+
+// escapeLabelValue escapes a label value per the exposition format
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
@@ -0,0 +1,261 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewPayload(t *testing.T) {
+	const size = 64 * 1024
+
+	random, err := newPayload(size, true)
+	if err != nil {
+		t.Fatalf("newPayload: %v", err)
+	}
+	if len(random) != size {
+		t.Fatalf("got %d bytes, want %d", len(random), size)
+	}
+	if bytes.Count(random, []byte{0}) == size {
+		t.Error("random payload is all zeros")
+	}
+	again, err := newPayload(size, true)
+	if err != nil {
+		t.Fatalf("newPayload: %v", err)
+	}
+	if bytes.Equal(random, again) {
+		t.Error("two random payloads are identical")
+	}
+
+	zeros, err := newPayload(size, false)
+	if err != nil {
+		t.Fatalf("newPayload: %v", err)
+	}
+	if !bytes.Equal(zeros, make([]byte, size)) {
+		t.Error("payload without RandomPayload is not all zeros")
+	}
+}
+
+func TestRunQualityTestConnections(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		config := newTestConfig(t)
+		config.NumConnections = n
+		if _, err := RunQualityTest(context.Background(), config); err == nil {
+			t.Errorf("NumConnections %d: got no error", n)
+		}
+	}
+
+	config := newTestConfig(t)
+	config.NumConnections = 100000
+	config.SkipUpload = true
+	result, err := RunQualityTest(context.Background(), config)
+	if err != nil {
+		t.Fatalf("NumConnections %d: %v", config.NumConnections, err)
+	}
+	if result.Connections != MaxConnections {
+		t.Errorf("got %d connections, want them capped at %d", result.Connections, MaxConnections)
+	}
+	if config.NumConnections != 100000 {
+		t.Errorf("caller's NumConnections changed to %d", config.NumConnections)
+	}
+}
+
+// TestRunQualityTestConcurrent runs two tests at once against separate
+// servers, one of them throttled, and checks that neither sees the other's
+// servers or rate limit
+func TestRunQualityTestConcurrent(t *testing.T) {
+	type run struct {
+		server *httptest.Server
+		hits   atomic.Int64
+		config *TestConfig
+		result *QualityResult
+		err    error
+	}
+	runs := []*run{{}, {}}
+	for _, r := range runs {
+		r := r
+		handler := NewServerHandler()
+		r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			r.hits.Add(1)
+			handler.ServeHTTP(w, req)
+		}))
+		defer r.server.Close()
+		r.config = ServerConfig(r.server.URL)
+		shortenTestConfig(r.config)
+	}
+	const throttle = 20
+	runs[0].config.MaxMbps = throttle
+
+	var wg sync.WaitGroup
+	for _, r := range runs {
+		wg.Add(1)
+		go func(r *run) {
+			defer wg.Done()
+			r.result, r.err = RunQualityTest(context.Background(), r.config)
+		}(r)
+	}
+	wg.Wait()
+
+	for i, r := range runs {
+		if r.err != nil {
+			t.Fatalf("run %d: %v", i, r.err)
+		}
+		if r.hits.Load() == 0 {
+			t.Errorf("run %d: its server got no requests", i)
+		}
+		for _, s := range append(r.result.DownlinkPerServer, r.result.UplinkPerServer...) {
+			if !strings.HasPrefix(s.Server, r.server.URL) {
+				t.Errorf("run %d: got throughput for another run's server %s", i, s.Server)
+			}
+		}
+	}
+	if got := runs[0].result.DownlinkCapacity; got > throttle*1.5 {
+		t.Errorf("throttled run got %v Mbps, want at most about %d", got, throttle)
+	}
+	if got := runs[1].result.DownlinkCapacity; got <= throttle*1.5 {
+		t.Errorf("unthrottled run got %v Mbps, want it unaffected by the other run's limit", got)
+	}
+}
+
+func TestConfigClone(t *testing.T) {
+	original := newTestConfig(t)
+	original.UploadChunkSizes = []int{64 * 1024, 256 * 1024}
+	original.Headers = http.Header{"Authorization": {"Bearer original"}}
+	original.TLSConfig = &tls.Config{ServerName: "original"}
+	downServer := original.TestServers[0]
+	upServer := original.UploadServers[0]
+
+	clone := original.Clone()
+	clone.TestServers[0] = "http://clone.invalid/down"
+	clone.TestServers = append(clone.TestServers, "http://clone.invalid/down2")
+	clone.UploadServers[0] = "http://clone.invalid/up"
+	clone.UploadChunkSizes[0] = 1
+	clone.Headers.Set("Authorization", "Bearer clone")
+	clone.Headers.Add("X-Clone", "1")
+	clone.TLSConfig.ServerName = "clone"
+
+	if len(original.TestServers) != 1 || original.TestServers[0] != downServer {
+		t.Errorf("original TestServers changed to %v", original.TestServers)
+	}
+	if original.UploadServers[0] != upServer {
+		t.Errorf("original UploadServers changed to %v", original.UploadServers)
+	}
+	if original.UploadChunkSizes[0] != 64*1024 {
+		t.Errorf("original UploadChunkSizes changed to %v", original.UploadChunkSizes)
+	}
+	if got := original.Headers.Get("Authorization"); got != "Bearer original" || original.Headers.Get("X-Clone") != "" {
+		t.Errorf("original Headers changed to %v", original.Headers)
+	}
+	if original.TLSConfig.ServerName != "original" {
+		t.Errorf("original TLSConfig.ServerName changed to %q", original.TLSConfig.ServerName)
+	}
+}
+
+// TestLatencyPhaseBudget checks that the idle and warm latency probes
+// together keep to the latency share of TotalBudget on a slow server
+func TestLatencyPhaseBudget(t *testing.T) {
+	const delay = 200 * time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(t)
+	config.LatencyServer = server.URL
+	config.TotalBudget = 2 * time.Second
+	config = prepareTestConfig(t, config)
+
+	ctx := context.Background()
+	start := time.Now()
+	if _, err := measureIdleLatency(ctx, config, server.URL, nil); err != nil {
+		t.Fatalf("measureIdleLatency: %v", err)
+	}
+	measureWarmLatency(ctx, config, server.URL, start)
+	// One probe may still be finishing when the window runs out
+	if elapsed, limit := time.Since(start), config.latencyWindow+delay; elapsed > limit {
+		t.Errorf("latency probes took %v, want at most %v", elapsed.Round(time.Millisecond), limit)
+	}
+}
+
+// TestVerifiesWithSystemRoots checks that a self-signed server reached with
+// InsecureSkipVerify is flagged as not verifying against the system roots
+func TestVerifiesWithSystemRoots(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.TLS == nil {
+		t.Fatal("response has no TLS state")
+	}
+	if verifiesWithSystemRoots(resp.TLS) {
+		t.Error("self-signed certificate verified against the system roots")
+	}
+	if verifiesWithSystemRoots(&tls.ConnectionState{}) {
+		t.Error("empty peer chain verified")
+	}
+}
+
+// TestPublishWebhookSchema checks that webhooks receive the versioned JSON
+// document, including the phase windows in seconds
+func TestPublishWebhookSchema(t *testing.T) {
+	var got ResultJSON
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	result := &QualityResult{
+		DownloadWindow: PhaseWindow{Configured: 10 * time.Second, Effective: 9500 * time.Millisecond},
+	}
+	if err := PublishWebhook(context.Background(), server.URL, result); err != nil {
+		t.Fatalf("PublishWebhook: %v", err)
+	}
+	if got.SchemaVersion != SchemaVersion {
+		t.Errorf("schema_version = %d, want %d", got.SchemaVersion, SchemaVersion)
+	}
+	if want := (PhaseWindowJSON{Configured: 10, Effective: 9.5}); got.DownloadWindow != want {
+		t.Errorf("download_window = %+v, want %+v", got.DownloadWindow, want)
+	}
+}
+
+// TestServerURLValidation checks that server URLs from the environment and
+// config files must be http or https URLs with a host
+func TestServerURLValidation(t *testing.T) {
+	for _, name := range []string{"NQ_DOWN_URL", "NQ_UP_URL", "NQ_LATENCY_URL"} {
+		t.Setenv(name, "example.com/path")
+		if err := ApplyEnv(DefaultConfig()); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: got error %v, want one naming the variable", name, err)
+		}
+		t.Setenv(name, "https://example.com/path")
+		if err := ApplyEnv(DefaultConfig()); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"latency_server": "ftp://example.com/ping"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "latency_server") {
+		t.Errorf("got error %v, want one naming latency_server", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !nohttp3
+
+package network
+
+import (
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// newHTTP3Transport returns an HTTP/3 round tripper over QUIC for a run,
+// along with a function closing its connections
+func newHTTP3Transport(config *TestConfig) (http.RoundTripper, func(), error) {
+	transport := &http3.RoundTripper{TLSClientConfig: config.TLSConfig.Clone()}
+	return transport, func() { transport.Close() }, nil
+}
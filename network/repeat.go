@@ -0,0 +1,123 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// RunQualityTestN performs n sequential tests and returns a result holding
+// the mean of each metric along with the individual results. Failed request
+// counts are summed rather than averaged. If ctx ends between or during
+// runs, the mean of the completed runs is returned with an error wrapping
+// ErrPartial; the interrupted run is discarded.
+func RunQualityTestN(ctx context.Context, config *TestConfig, n int) (*QualityResult, []*QualityResult, error) {
+	if n < 1 {
+		return nil, nil, fmt.Errorf("number of runs must be at least 1, got %d", n)
+	}
+
+	var results []*QualityResult
+	for i := 0; i < n; i++ {
+		if ctx.Err() != nil {
+			return stopEarly(ctx, results, n, ctx.Err())
+		}
+		result, err := RunQualityTest(ctx, config)
+		if err != nil {
+			return stopEarly(ctx, results, n, fmt.Errorf("run %d: %w", i+1, err))
+		}
+		results = append(results, result)
+	}
+
+	return meanResult(results), results, nil
+}
+
+// stopEarly returns what RunQualityTestN completed before err, keeping the
+// completed runs only when err was caused by ctx ending
+func stopEarly(ctx context.Context, results []*QualityResult, n int, err error) (*QualityResult, []*QualityResult, error) {
+	if ctx.Err() == nil || len(results) == 0 {
+		return nil, results, err
+	}
+	return meanResult(results), results,
+		fmt.Errorf("%w: completed %d of %d runs: %w", ErrPartial, len(results), n, err)
+}
+
+// meanFields returns the float metrics averaged by meanResult
+func (r *QualityResult) meanFields() []*float64 {
+	return []*float64{
+		&r.UplinkCapacity, &r.DownlinkCapacity, &r.DownlinkAverage, &r.IdleLatency, &r.JitterMs, &r.WarmLatencyMs,
+		&r.LatencyP50Ms, &r.LatencyP95Ms, &r.LatencyP99Ms,
+		&r.DNSMs, &r.ConnectMs, &r.TLSMs, &r.TTFBMs,
+		&r.ServerProcessingMs, &r.AdjustedLatencyMs,
+		&r.ResponsivenessMs, &r.LoadedLatencyP95Ms, &r.LoadedTTFBMs, &r.BidirectionalLatencyMs, &r.UploadLoadedLatencyMs,
+		&r.LatencyIncreaseMs,
+		&r.DownlinkP50, &r.DownlinkP90, &r.DownlinkPeak, &r.UplinkP90, &r.UplinkPeak,
+		&r.DownloadSeconds, &r.UploadSeconds, &r.EstimatedLossPercent,
+	}
+}
+
+// meanResult averages results metric by metric. Labels are derived again
+// from the averaged metrics, caveats are merged, and the timestamp is that
+// of the last run.
+func meanResult(results []*QualityResult) *QualityResult {
+	mean := &QualityResult{}
+	sums := mean.meanFields()
+	count := float64(len(results))
+
+	var rpm, downloadBytes, uploadBytes int64
+	var downloadWindow, uploadWindow time.Duration
+	graded := false
+	mean.Stabilized = true
+	for _, r := range results {
+		for i, v := range r.meanFields() {
+			*sums[i] += *v
+		}
+		rpm += int64(r.RPM)
+		downloadBytes += r.DownloadBytes
+		uploadBytes += r.UploadBytes
+		downloadWindow += r.DownloadWindow.Effective
+		uploadWindow += r.UploadWindow.Effective
+		mean.DownloadErrors += r.DownloadErrors
+		mean.UploadErrors += r.UploadErrors
+		mean.LatencyErrors += r.LatencyErrors
+		for _, c := range r.Caveats {
+			mean.addCaveat(c)
+		}
+		graded = graded || r.BufferbloatGrade != ""
+		mean.Stabilized = mean.Stabilized && r.Stabilized
+		mean.ByteCapReached = mean.ByteCapReached || r.ByteCapReached
+		if r.ShapingSuspected {
+			mean.ShapingSuspected = true
+			mean.ShapingReason = r.ShapingReason
+		}
+	}
+
+	for _, v := range sums {
+		*v = round3(*v / count)
+	}
+	last := results[len(results)-1]
+	mean.Timestamp = last.Timestamp
+	mean.RPM = int(math.Round(float64(rpm) / count))
+	mean.DownloadBytes = int64(math.Round(float64(downloadBytes) / count))
+	mean.UploadBytes = int64(math.Round(float64(uploadBytes) / count))
+	mean.DownloadWindow = PhaseWindow{
+		Configured: last.DownloadWindow.Configured,
+		Effective:  downloadWindow / time.Duration(len(results)),
+	}
+	mean.UploadWindow = PhaseWindow{
+		Configured: last.UploadWindow.Configured,
+		Effective:  uploadWindow / time.Duration(len(results)),
+	}
+	mean.Responsiveness = responsiveness(mean.LoadedLatencyP95Ms)
+	if graded {
+		ratio := latencyRatio(mean.IdleLatency, mean.ResponsivenessMs)
+		mean.BufferbloatRatio = round3(ratio)
+		mean.BufferbloatGrade = bufferbloatGrade(ratio)
+	}
+	mean.ServerLocation = last.ServerLocation
+	mean.Connections = last.Connections
+	mean.Config = last.Config
+	mean.TLSVersion = last.TLSVersion
+	mean.CipherSuite = last.CipherSuite
+	return mean
+}
@@ -0,0 +1,40 @@
+package network
+
+import (
+	"context"
+	"time"
+)
+
+// retryBaseDelay is the wait before the first retry; it doubles after each
+// further attempt
+const retryBaseDelay = 100 * time.Millisecond
+
+// retry calls fn until it succeeds, ctx is done, or it has been retried
+// maxRetries times, backing off between attempts. The last error from fn is
+// returned.
+func retry(ctx context.Context, maxRetries int, fn func() error) error {
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxRetries || ctx.Err() != nil {
+			return err
+		}
+		if sleepContext(ctx, delay) != nil {
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// sleepContext waits for d or until ctx is done, returning ctx.Err() in the
+// latter case
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,124 @@
+package network
+
+import (
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// defaultSampleInterval is used when TestConfig.SampleInterval is unset
+const defaultSampleInterval = 250 * time.Millisecond
+
+// sampleThroughput periodically reads counter and records the throughput in
+// Mbps over each interval. The returned stop function ends sampling and
+// returns the collected samples.
+func sampleThroughput(counter *atomic.Int64, interval time.Duration) (stop func() []float64) {
+	if interval <= 0 {
+		interval = defaultSampleInterval
+	}
+
+	done := make(chan struct{})
+	result := make(chan []float64, 1)
+	go func() {
+		var samples []float64
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := time.Now()
+		lastBytes := counter.Load()
+		for {
+			select {
+			case <-done:
+				result <- samples
+				return
+			case now := <-ticker.C:
+				bytes := counter.Load()
+				samples = append(samples, toMbps(bytes-lastBytes, now.Sub(last).Seconds()))
+				last, lastBytes = now, bytes
+			}
+		}
+	}()
+
+	return func() []float64 {
+		close(done)
+		return <-result
+	}
+}
+
+// percentile returns the p-th percentile (0-100) of values using linear
+// interpolation between closest ranks, or 0 when values is empty
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// peak returns the largest value, or 0 when values is empty
+func peak(values []float64) float64 {
+	var max float64
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// Stability detection for stabilizedRate
+const (
+	stableWindow = 4   // consecutive samples that must agree
+	stableMaxCV  = 0.1 // largest coefficient of variation considered stable
+)
+
+// stabilizedRate returns the mean throughput from the first point where a
+// sliding window of samples settles, ignoring the slow-start ramp before
+// it. ok is false when throughput never stabilized.
+func stabilizedRate(samples []float64) (rate float64, ok bool) {
+	for start := 0; start+stableWindow <= len(samples); start++ {
+		mean, stddev := meanStddev(samples[start : start+stableWindow])
+		if mean > 0 && stddev/mean <= stableMaxCV {
+			rate, _ = meanStddev(samples[start:])
+			return rate, true
+		}
+	}
+	return 0, false
+}
+
+// tailStable reports whether throughput had settled by the end of a phase:
+// the last third of the samples, at least stableWindow of them, must vary
+// no more than stableMaxCV. A phase that ends still ramping up fails.
+func tailStable(samples []float64) bool {
+	tail := samples[len(samples)-len(samples)/3:]
+	if len(tail) < stableWindow {
+		return false
+	}
+	mean, stddev := meanStddev(tail)
+	return mean > 0 && stddev/mean <= stableMaxCV
+}
+
+// meanStddev returns the mean and population standard deviation of values
+func meanStddev(values []float64) (mean, stddev float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		stddev += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(stddev / float64(len(values)))
+}
@@ -0,0 +1,145 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is the version of the JSON document written by
+// MarshalResultJSON. It only changes when fields are removed, renamed, or
+// change meaning; new fields may be added within a version.
+const SchemaVersion = 1
+
+// ResultJSON is the stable JSON representation of a QualityResult, kept
+// separate from it so refactoring the result does not break stored data.
+// Throughput is in Mbps (10^6 bits per second), durations in milliseconds
+// unless the name says otherwise. The download_window and upload_window
+// objects give the configured and effective phase windows in seconds.
+type ResultJSON struct {
+	SchemaVersion int       `json:"schema_version"`
+	Timestamp     time.Time `json:"timestamp"` // RFC 3339, when the test completed
+
+	DownlinkMbps        float64 `json:"downlink_mbps"`         // once throughput stabilized
+	DownlinkAverageMbps float64 `json:"downlink_average_mbps"` // over the whole window
+	UplinkMbps          float64 `json:"uplink_mbps"`
+
+	IdleLatencyMs float64 `json:"idle_latency_ms"`
+	JitterMs      float64 `json:"jitter_ms"`
+	WarmLatencyMs float64 `json:"warm_latency_ms"` // over a reused connection, 0 if not measured
+	LatencyP50Ms  float64 `json:"latency_p50_ms"`
+	LatencyP95Ms  float64 `json:"latency_p95_ms"`
+	LatencyP99Ms  float64 `json:"latency_p99_ms"`
+
+	LoadedLatencyMs       float64 `json:"loaded_latency_ms"`
+	LoadedLatencyP95Ms    float64 `json:"loaded_latency_p95_ms"`
+	LoadedTTFBMs          float64 `json:"loaded_ttfb_ms"`
+	UploadLoadedLatencyMs float64 `json:"upload_loaded_latency_ms"` // during the upload alone, 0 if not measured
+	LatencyIncreaseMs     float64 `json:"latency_increase_ms"`
+	BufferbloatRatio      float64 `json:"bufferbloat_ratio"` // loaded/idle latency, 0 if not measured
+	RPM                   int     `json:"rpm"`
+	Responsiveness        string  `json:"responsiveness"`    // High, Medium, or Low
+	BufferbloatGrade      string  `json:"bufferbloat_grade"` // A to F, empty if not measured
+	EstimatedLossPercent  float64 `json:"estimated_loss_percent"`
+
+	Quality    string           `json:"quality"` // Excellent, Good, Fair, or Poor
+	Score      int              `json:"score"`   // 0 to MaxScore
+	Experience ExperienceScores `json:"experience"`
+
+	DownloadBytes   int64   `json:"download_bytes"`
+	DownloadSeconds float64 `json:"download_seconds"`
+	UploadBytes     int64   `json:"upload_bytes"`
+	UploadSeconds   float64 `json:"upload_seconds"`
+
+	DownloadWindow PhaseWindowJSON `json:"download_window"`
+	UploadWindow   PhaseWindowJSON `json:"upload_window"`
+
+	DownloadErrors int `json:"download_errors"`
+	UploadErrors   int `json:"upload_errors"`
+	LatencyErrors  int `json:"latency_errors"`
+
+	ServerLocation   string   `json:"server_location"` // empty when unknown
+	Connections      int      `json:"connections"`
+	ByteCapReached   bool     `json:"byte_cap_reached"`
+	Stabilized       bool     `json:"stabilized"` // throughput settled before each phase ended
+	ShapingSuspected bool     `json:"shaping_suspected"`
+	ShapingReason    string   `json:"shaping_reason"` // empty unless shaping is suspected
+	Caveats          []string `json:"caveats"`        // never null
+
+	Config ConfigSnapshot `json:"config"` // effective settings of the run
+}
+
+// NewResultJSON converts a result to its stable JSON representation
+func NewResultJSON(r *QualityResult) ResultJSON {
+	score, quality := r.Score()
+	caveats := r.Caveats
+	if caveats == nil {
+		caveats = []string{}
+	}
+	return ResultJSON{
+		SchemaVersion:         SchemaVersion,
+		Timestamp:             r.Timestamp.UTC(),
+		DownlinkMbps:          r.DownlinkCapacity,
+		DownlinkAverageMbps:   r.DownlinkAverage,
+		UplinkMbps:            r.UplinkCapacity,
+		IdleLatencyMs:         r.IdleLatency,
+		JitterMs:              r.JitterMs,
+		WarmLatencyMs:         r.WarmLatencyMs,
+		LatencyP50Ms:          r.LatencyP50Ms,
+		LatencyP95Ms:          r.LatencyP95Ms,
+		LatencyP99Ms:          r.LatencyP99Ms,
+		LoadedLatencyMs:       r.ResponsivenessMs,
+		LoadedLatencyP95Ms:    r.LoadedLatencyP95Ms,
+		LoadedTTFBMs:          r.LoadedTTFBMs,
+		UploadLoadedLatencyMs: r.UploadLoadedLatencyMs,
+		LatencyIncreaseMs:     r.LatencyIncreaseMs,
+		BufferbloatRatio:      r.BufferbloatRatio,
+		RPM:                   r.RPM,
+		Responsiveness:        r.Responsiveness,
+		BufferbloatGrade:      r.BufferbloatGrade,
+		EstimatedLossPercent:  r.EstimatedLossPercent,
+		Quality:               quality,
+		Score:                 score,
+		Experience:            r.ExperienceScores(),
+		DownloadBytes:         r.DownloadBytes,
+		DownloadSeconds:       r.DownloadSeconds,
+		UploadBytes:           r.UploadBytes,
+		UploadSeconds:         r.UploadSeconds,
+		DownloadWindow:        newPhaseWindowJSON(r.DownloadWindow),
+		UploadWindow:          newPhaseWindowJSON(r.UploadWindow),
+		DownloadErrors:        r.DownloadErrors,
+		UploadErrors:          r.UploadErrors,
+		LatencyErrors:         r.LatencyErrors,
+		ServerLocation:        r.ServerLocation,
+		Connections:           r.Connections,
+		ByteCapReached:        r.ByteCapReached,
+		Stabilized:            r.Stabilized,
+		ShapingSuspected:      r.ShapingSuspected,
+		ShapingReason:         r.ShapingReason,
+		Caveats:               caveats,
+		Config:                r.Config,
+	}
+}
+
+// PhaseWindowJSON is the JSON representation of a PhaseWindow, in seconds
+type PhaseWindowJSON struct {
+	Configured float64 `json:"configured"`
+	Effective  float64 `json:"effective"` // used for the Mbps calculation
+}
+
+// newPhaseWindowJSON converts a phase window to seconds
+func newPhaseWindowJSON(w PhaseWindow) PhaseWindowJSON {
+	return PhaseWindowJSON{
+		Configured: round3(w.Configured.Seconds()),
+		Effective:  round3(w.Effective.Seconds()),
+	}
+}
+
+// MarshalResultJSON encodes the result as an indented ResultJSON document
+// tagged with SchemaVersion, the format to use for storing results
+func MarshalResultJSON(r *QualityResult) ([]byte, error) {
+	if r == nil {
+		return nil, fmt.Errorf("no result to export")
+	}
+	return json.MarshalIndent(NewResultJSON(r), "", "  ")
+}
@@ -0,0 +1,96 @@
+package network
+
+import "math"
+
+// Score labels, from best to worst
+const (
+	ScoreExcellent = "Excellent"
+	ScoreGood      = "Good"
+	ScoreFair      = "Fair"
+	ScorePoor      = "Poor"
+)
+
+// Download capacity earning 3, 2, or 1 points, in Mbps
+const (
+	DownlinkScoreHigh = 50.0
+	DownlinkScoreMid  = 25.0
+	DownlinkScoreLow  = 10.0
+)
+
+// Upload capacity earning 3, 2, or 1 points, in Mbps
+const (
+	UplinkScoreHigh = 20.0
+	UplinkScoreMid  = 10.0
+	UplinkScoreLow  = 5.0
+)
+
+// Idle latency earning 3, 2, or 1 points, in milliseconds
+const (
+	LatencyScoreHigh = 20.0
+	LatencyScoreMid  = 50.0
+	LatencyScoreLow  = 100.0
+)
+
+// Minimum scores for each label; anything lower is ScorePoor
+const (
+	MinExcellentScore = 8
+	MinGoodScore      = 6
+	MinFairScore      = 4
+)
+
+// MaxScore is the highest value Score can return
+const MaxScore = 9
+
+// Score rates the result from 0 to MaxScore based on download and upload
+// capacity and idle latency, and returns the matching label. A phase
+// skipped with SkipDownload or SkipUpload is left out, and the points of
+// the others are scaled up to MaxScore.
+func (r *QualityResult) Score() (int, string) {
+	points, possible := 0, 3
+
+	if !r.skipped(PhaseDownload) {
+		possible += 3
+		switch {
+		case r.DownlinkCapacity > DownlinkScoreHigh:
+			points += 3
+		case r.DownlinkCapacity > DownlinkScoreMid:
+			points += 2
+		case r.DownlinkCapacity > DownlinkScoreLow:
+			points += 1
+		}
+	}
+
+	if !r.skipped(PhaseUpload) {
+		possible += 3
+		switch {
+		case r.UplinkCapacity > UplinkScoreHigh:
+			points += 3
+		case r.UplinkCapacity > UplinkScoreMid:
+			points += 2
+		case r.UplinkCapacity > UplinkScoreLow:
+			points += 1
+		}
+	}
+
+	switch {
+	case r.IdleLatency < LatencyScoreHigh:
+		points += 3
+	case r.IdleLatency < LatencyScoreMid:
+		points += 2
+	case r.IdleLatency < LatencyScoreLow:
+		points += 1
+	}
+
+	score := int(math.Round(float64(points*MaxScore) / float64(possible)))
+
+	switch {
+	case score >= MinExcellentScore:
+		return score, ScoreExcellent
+	case score >= MinGoodScore:
+		return score, ScoreGood
+	case score >= MinFairScore:
+		return score, ScoreFair
+	default:
+		return score, ScorePoor
+	}
+}
@@ -0,0 +1,43 @@
+package network
+
+import "testing"
+
+func TestScoreSkippedPhase(t *testing.T) {
+	tests := []struct {
+		name   string
+		result QualityResult
+		score  int
+		label  string
+	}{
+		{
+			name:   "both phases",
+			result: QualityResult{DownlinkCapacity: 100, UplinkCapacity: 30, IdleLatency: 10},
+			score:  9,
+			label:  ScoreExcellent,
+		},
+		{
+			name:   "upload skipped",
+			result: QualityResult{DownlinkCapacity: 100, IdleLatency: 10, Config: ConfigSnapshot{SkipUpload: true}},
+			score:  9,
+			label:  ScoreExcellent,
+		},
+		{
+			name:   "download skipped",
+			result: QualityResult{UplinkCapacity: 15, IdleLatency: 30, Config: ConfigSnapshot{SkipDownload: true}},
+			score:  6,
+			label:  ScoreGood,
+		},
+		{
+			name:   "upload skipped on a slow link",
+			result: QualityResult{DownlinkCapacity: 5, IdleLatency: 200, Config: ConfigSnapshot{SkipUpload: true}},
+			score:  0,
+			label:  ScorePoor,
+		},
+	}
+	for _, tt := range tests {
+		score, label := tt.result.Score()
+		if score != tt.score || label != tt.label {
+			t.Errorf("%s: got %d (%s), want %d (%s)", tt.name, score, label, tt.score, tt.label)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package network
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Download sizes served by NewServerHandler when the bytes parameter is
+// missing or invalid, and the most it serves per request
+const (
+	serverDownloadBytes    = 10_000_000
+	maxServerDownloadBytes = 1 << 30
+)
+
+// NewServerHandler returns the handler of a self-hosted test server:
+// GET /down?bytes=N streams N zero bytes, POST /up discards the request
+// body, and /ping answers with an empty 204. Pointing a TestConfig at it,
+// e.g. with ServerConfig, measures the path between two machines without
+// any external service.
+func NewServerHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		size, err := strconv.ParseInt(r.URL.Query().Get("bytes"), 10, 64)
+		if err != nil || size <= 0 {
+			size = serverDownloadBytes
+		}
+		size = min(size, maxServerDownloadBytes)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.Header().Set("Cache-Control", "no-store")
+		io.CopyN(w, zeroReader{}, size)
+	})
+	mux.HandleFunc("/up", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	})
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// ServerConfig returns a DefaultConfig that measures against a server
+// running NewServerHandler at baseURL, e.g. "http://192.168.1.20:8080"
+func ServerConfig(baseURL string) *TestConfig {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	config := DefaultConfig()
+	config.TestServers = []string{baseURL + "/down?bytes=" + strconv.Itoa(serverDownloadBytes)}
+	config.UploadServers = []string{baseURL + "/up"}
+	config.LatencyServer = baseURL + "/ping"
+	config.CaptivePortalURL = baseURL + "/ping"
+	return config
+}
+
+// zeroReader is an endless source of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
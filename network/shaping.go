@@ -0,0 +1,81 @@
+package network
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// shaperRates are plan and shaper rates in Mbps that a throttled download
+// commonly settles at
+var shaperRates = []float64{1, 2, 3, 5, 8, 10, 12, 15, 20, 25, 30, 40, 50, 60, 75, 80, 100, 150, 200, 250, 300, 400, 500, 1000}
+
+const (
+	// plateauTolerance is how close to a shaper rate a download must
+	// settle, as a fraction of the rate
+	plateauTolerance = 0.015
+
+	// plateauMaxCV is the coefficient of variation below which the end of
+	// a download counts as a flat plateau, stricter than stableMaxCV since
+	// a shaper holds the rate almost exactly
+	plateauMaxCV = 0.03
+
+	// minTrendProbes is the fewest loaded latency probes a latency trend
+	// is judged on
+	minTrendProbes = 5
+
+	// minTrendCorrelation is how closely loaded latency must follow a
+	// straight line over time to count as climbing steadily
+	minTrendCorrelation = 0.8
+
+	// minTrendRiseMs is how far loaded latency must climb over the phase
+	minTrendRiseMs = 20
+)
+
+// detectShaping looks for signs of a traffic shaper in the download phase:
+// throughput flattening at a round plan rate, or loaded latency climbing
+// steadily while throughput stays flat as the shaper's queue fills. It
+// returns a short explanation, or an empty string when neither shows. Both
+// are heuristics; a congested link can look the same.
+func detectShaping(download transferStats, loaded loadedLatency) string {
+	var reasons []string
+
+	tail := download.samples[len(download.samples)-len(download.samples)/3:]
+	if len(tail) >= stableWindow {
+		mean, stddev := meanStddev(tail)
+		if mean > 0 && stddev/mean <= plateauMaxCV {
+			for _, rate := range shaperRates {
+				if math.Abs(mean-rate) <= rate*plateauTolerance {
+					reasons = append(reasons, fmt.Sprintf("download held flat at %.1f Mbps, a common plan or shaper rate", mean))
+					break
+				}
+			}
+		}
+	}
+
+	if tailStable(download.samples) && len(loaded.samples) >= minTrendProbes {
+		slope, r := linearTrend(loaded.offsets, loaded.samples)
+		rise := slope * (loaded.offsets[len(loaded.offsets)-1] - loaded.offsets[0])
+		if r >= minTrendCorrelation && rise >= minTrendRiseMs {
+			reasons = append(reasons, fmt.Sprintf("loaded latency climbed steadily by %.0f ms while throughput stayed flat", rise))
+		}
+	}
+
+	return strings.Join(reasons, "; ")
+}
+
+// linearTrend fits y against x by least squares and returns the slope and
+// the correlation coefficient, both 0 when either does not vary
+func linearTrend(x, y []float64) (slope, r float64) {
+	meanX, stddevX := meanStddev(x)
+	meanY, stddevY := meanStddev(y)
+	if stddevX == 0 || stddevY == 0 {
+		return 0, 0
+	}
+	var cov float64
+	for i := range x {
+		cov += (x[i] - meanX) * (y[i] - meanY)
+	}
+	cov /= float64(len(x))
+	return cov / (stddevX * stddevX), cov / (stddevX * stddevY)
+}
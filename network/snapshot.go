@@ -0,0 +1,105 @@
+package network
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ConfigSnapshot records the effective settings a result was measured with,
+// so stored results tell a 5 second quick test from a 30 second full one.
+// Durations are those actually used, after TotalBudget was split up, and
+// the connection count is the one calibrated by AutoConnections. Hooks,
+// transports, TLS settings, and header values are left out, and proxy
+// passwords are redacted.
+type ConfigSnapshot struct {
+	Version string `json:"version"` // networkquality version
+
+	TestDurationSeconds   float64 `json:"test_duration_seconds"`          // download window
+	UploadDurationSeconds float64 `json:"upload_duration_seconds"`        // upload window, per chunk size
+	WarmupSeconds         float64 `json:"warmup_seconds"`                 // before each window
+	TotalBudgetSeconds    float64 `json:"total_budget_seconds,omitempty"` // when the phases were derived from it
+
+	NumConnections  int  `json:"num_connections"`
+	AutoConnections bool `json:"auto_connections,omitempty"`
+	LatencySamples  int  `json:"latency_samples"`
+
+	TestServers      []string `json:"test_servers"`
+	UploadServers    []string `json:"upload_servers"`
+	LatencyServer    string   `json:"latency_server"`
+	UploadChunkSize  int      `json:"upload_chunk_size"`
+	UploadChunkSizes []int    `json:"upload_chunk_sizes,omitempty"`
+	StreamingUpload  bool     `json:"streaming_upload,omitempty"`
+
+	Bidirectional bool `json:"bidirectional,omitempty"`
+	SkipDownload  bool `json:"skip_download,omitempty"`
+	SkipUpload    bool `json:"skip_upload,omitempty"`
+	BurstMode     bool `json:"burst_mode,omitempty"`
+	BurstCount    int  `json:"burst_count,omitempty"`
+
+	IPVersion   string   `json:"ip_version,omitempty"`
+	HTTP3       bool     `json:"http3,omitempty"`
+	ProxyURL    string   `json:"proxy_url,omitempty"`
+	SOCKS5      string   `json:"socks5,omitempty"`
+	UserAgent   string   `json:"user_agent"`
+	HeaderNames []string `json:"header_names,omitempty"`
+
+	MaxMbps  float64 `json:"max_mbps,omitempty"`
+	MaxBytes int64   `json:"max_bytes,omitempty"`
+}
+
+// newConfigSnapshot captures a prepared run config
+func newConfigSnapshot(c *TestConfig) ConfigSnapshot {
+	samples := c.LatencySamples
+	if samples <= 0 {
+		samples = defaultLatencySamples
+	}
+	s := ConfigSnapshot{
+		Version:               Version,
+		TestDurationSeconds:   c.TestDuration.Seconds(),
+		UploadDurationSeconds: c.UploadDuration().Seconds(),
+		WarmupSeconds:         c.warmup().Seconds(),
+		TotalBudgetSeconds:    c.TotalBudget.Seconds(),
+		NumConnections:        c.NumConnections,
+		AutoConnections:       c.AutoConnections,
+		LatencySamples:        samples,
+		TestServers:           slices.Clone(c.TestServers),
+		UploadServers:         slices.Clone(c.UploadServers),
+		LatencyServer:         c.LatencyURL(),
+		UploadChunkSize:       c.UploadChunkSize,
+		UploadChunkSizes:      slices.Clone(c.UploadChunkSizes),
+		StreamingUpload:       c.StreamingUpload,
+		Bidirectional:         c.Bidirectional,
+		SkipDownload:          c.SkipDownload,
+		SkipUpload:            c.SkipUpload,
+		BurstMode:             c.BurstMode,
+		IPVersion:             c.IPVersion,
+		HTTP3:                 c.HTTP3,
+		ProxyURL:              redactURL(c.ProxyURL),
+		SOCKS5:                redactURL(c.SOCKS5),
+		UserAgent:             c.userAgent(),
+		MaxMbps:               c.MaxMbps,
+		MaxBytes:              c.MaxBytes,
+	}
+	if c.BurstMode {
+		s.BurstCount = c.burstCount()
+	}
+	for name := range c.Headers {
+		s.HeaderNames = append(s.HeaderNames, name)
+	}
+	slices.Sort(s.HeaderNames)
+	return s
+}
+
+// redactURL hides the password in a proxy URL; values that are not URLs,
+// such as a SOCKS5 host:port, are returned unchanged
+func redactURL(value string) string {
+	if !strings.Contains(value, "://") {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,49 @@
+package network
+
+import "context"
+
+// PhaseComplete is the phase reported by the final QualityUpdate
+const PhaseComplete = "complete"
+
+// QualityUpdate is emitted by RunQualityTestStream as each phase finishes
+type QualityUpdate struct {
+	Phase  string         // phase that just finished, or PhaseComplete
+	Result *QualityResult // snapshot of the results measured so far
+	Err    error          // set on the final update if the test failed; Result is kept with ErrPartial
+	Done   bool           // true for the final update
+}
+
+// numUpdates is the most updates a single run emits: one per phase plus
+// the final update
+const numUpdates = 4
+
+// RunQualityTestStream starts a network quality test and returns a channel
+// that receives an update after the idle latency, download, and upload
+// phases, followed by a final update with Done set. The channel is closed
+// once the test ends, including when ctx is cancelled. Configuration errors
+// are returned immediately. Like RunQualityTest, the run is bounded by
+// twice its expected length plus 30 seconds.
+func RunQualityTestStream(ctx context.Context, config *TestConfig) (<-chan QualityUpdate, error) {
+	config, cleanup, err := prepareConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Buffered so the test never blocks on a slow or departed reader
+	updates := make(chan QualityUpdate, numUpdates)
+	go func() {
+		defer close(updates)
+		defer cleanup()
+
+		// A hung server cannot hold the run far past its expected length
+		ctx, cancel := context.WithTimeout(ctx, config.maxRunTime())
+		defer cancel()
+
+		result, err := runPhases(ctx, config, func(phase string, result *QualityResult) {
+			updates <- QualityUpdate{Phase: phase, Result: result.snapshot()}
+		})
+		updates <- QualityUpdate{Phase: PhaseComplete, Result: result, Err: err, Done: true}
+	}()
+
+	return updates, nil
+}
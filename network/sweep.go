@@ -0,0 +1,75 @@
+package network
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultSweepCounts are the connection counts ConnectionSweep tries when
+// none are given
+var DefaultSweepCounts = []int{1, 2, 4, 8, 16}
+
+// ConnectionSweep runs a download measurement at each connection count in
+// turn, each lasting TestDuration after warmup on fresh connections unless
+// Transport is set, and returns the throughput in Mbps keyed by count.
+// Comparing the entries shows whether more parallelism still helps or has
+// hit diminishing returns. If ctx ends after at least one count was
+// measured, those are returned with an error wrapping ErrPartial.
+func ConnectionSweep(ctx context.Context, config *TestConfig, counts []int) (map[int]float64, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if len(counts) == 0 {
+		counts = DefaultSweepCounts
+	}
+	for _, n := range counts {
+		if n < 1 {
+			return nil, fmt.Errorf("number of connections must be at least 1, got %d", n)
+		}
+	}
+
+	results := make(map[int]float64, len(counts))
+	for _, n := range counts {
+		if _, ok := results[n]; ok {
+			continue
+		}
+
+		mbps, err := sweepDownload(ctx, config, n)
+		if err != nil {
+			err = fmt.Errorf("%d connections: %w", n, err)
+			if ctx.Err() != nil && len(results) > 0 {
+				return results, fmt.Errorf("%w: %w", ErrPartial, err)
+			}
+			return nil, err
+		}
+		results[n] = mbps
+	}
+	return results, nil
+}
+
+// sweepDownload measures download throughput with n connections
+func sweepDownload(ctx context.Context, config *TestConfig, n int) (float64, error) {
+	sweepConfig := *config
+	sweepConfig.NumConnections = n
+	// The sweep is about throughput only, so nothing is probed under load
+	sweepConfig.LossProbes = 0
+	// Each count runs for TestDuration rather than a share of a total
+	sweepConfig.TotalBudget = 0
+
+	runConfig, cleanup, err := prepareConfig(&sweepConfig)
+	if err != nil {
+		return 0, err
+	}
+	defer cleanup()
+
+	runConfig.debug("connection sweep step", "connections", runConfig.NumConnections)
+	download, _, err := runDownload(ctx, runConfig, "")
+	if err != nil {
+		return 0, err
+	}
+	// Reported like DownlinkCapacity, once throughput stabilized
+	if stable, ok := stabilizedRate(download.samples); ok {
+		return round3(stable), nil
+	}
+	return download.mbps, nil
+}
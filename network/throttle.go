@@ -0,0 +1,84 @@
+package network
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// minThrottleBurst is the smallest burst the rate limiter allows, so a
+// single read never has to wait for more than one bucket's worth of tokens
+const minThrottleBurst = 64 * 1024
+
+// rateLimiter is a token bucket shared by every connection of a phase,
+// refilled at a fixed byte rate. A nil limiter never waits.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter for mbps, or nil when mbps is not
+// positive
+func newRateLimiter(mbps float64) *rateLimiter {
+	if mbps <= 0 {
+		return nil
+	}
+	rate := mbps * 1000000 / 8
+	// A tenth of a second of traffic smooths bursts without letting the
+	// rate overshoot for long
+	burst := max(rate/10, minThrottleBurst)
+	return &rateLimiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait takes n tokens from the bucket, sleeping until the debt is repaid
+// when the bucket runs dry
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	return sleepContext(ctx, delay)
+}
+
+// reader returns r throttled by the limiter. Downloads slow down because
+// the unread data backs up through TCP flow control.
+func (l *rateLimiter) reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &throttledReader{ctx: ctx, r: r, limiter: l}
+}
+
+// throttledReader is the io.Reader returned by rateLimiter.reader
+type throttledReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	// Reads never exceed the burst so each wait stays short
+	if len(p) > int(t.limiter.burst) {
+		p = p[:int(t.limiter.burst)]
+	}
+	n, err := t.r.Read(p)
+	if waitErr := t.limiter.wait(t.ctx, n); waitErr != nil && err == nil {
+		err = waitErr
+	}
+	return n, err
+}
@@ -0,0 +1,157 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// probeTimings records the phases of a single request as reported by
+// httptrace. Phases that did not happen, such as DNS on a reused
+// connection, stay zero.
+type probeTimings struct {
+	mu      sync.Mutex
+	dns     time.Duration
+	connect time.Duration
+	tls     time.Duration
+	ttfb    time.Duration
+	server  time.Duration // from the Server-Timing response header
+
+	tlsVersion, cipherSuite string               // negotiated TLS parameters, if any
+	tlsState                *tls.ConnectionState // for checking the certificates
+	reused                  bool                 // sent over an existing connection
+
+	dnsStart, connectStart, tlsStart, wroteRequest time.Time
+}
+
+// withTimingTrace returns a context that records request phases into t
+func withTimingTrace(ctx context.Context, t *probeTimings) context.Context {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = info.Reused
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.dns = time.Since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			if err == nil {
+				t.connect = time.Since(t.connectStart)
+			}
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.mu.Lock()
+			if err == nil {
+				t.tls = time.Since(t.tlsStart)
+			}
+			t.mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			t.wroteRequest = time.Now()
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			if !t.wroteRequest.IsZero() {
+				t.ttfb = time.Since(t.wroteRequest)
+			}
+			t.mu.Unlock()
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace)
+}
+
+// timingBreakdown averages each request phase over the probes in which it
+// occurred
+type timingBreakdown struct {
+	dns, connect, tls, ttfb, server phaseAverage
+
+	tlsVersion, cipherSuite string               // from the first probe over TLS
+	tlsState                *tls.ConnectionState // of that probe
+}
+
+// add records the phases of one completed probe
+func (b *timingBreakdown) add(t *probeTimings) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b.dns.add(t.dns)
+	b.connect.add(t.connect)
+	b.tls.add(t.tls)
+	b.ttfb.add(t.ttfb)
+	b.server.add(t.server)
+	if b.tlsVersion == "" {
+		b.tlsVersion, b.cipherSuite, b.tlsState = t.tlsVersion, t.cipherSuite, t.tlsState
+	}
+}
+
+// serverTiming returns the server processing time from a Server-Timing
+// header such as "cfRequestDuration;dur=12.3, db;dur=4". Metrics may nest,
+// so the longest duration is taken as the total rather than the sum.
+func serverTiming(h http.Header) time.Duration {
+	var longest float64
+	for _, header := range h.Values("Server-Timing") {
+		for _, metric := range strings.Split(header, ",") {
+			params := strings.Split(metric, ";")
+			for _, param := range params[1:] {
+				name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(name, "dur") {
+					continue
+				}
+				ms, err := strconv.ParseFloat(strings.Trim(value, `"`), 64)
+				if err == nil && ms > longest {
+					longest = ms
+				}
+			}
+		}
+	}
+	return time.Duration(longest * float64(time.Millisecond))
+}
+
+// phaseAverage averages the non-zero durations it is given
+type phaseAverage struct {
+	total time.Duration
+	count int
+}
+
+func (a *phaseAverage) add(d time.Duration) {
+	if d > 0 {
+		a.total += d
+		a.count++
+	}
+}
+
+// ms returns the average in milliseconds, or 0 if the phase never occurred
+func (a *phaseAverage) ms() float64 {
+	if a.count == 0 {
+		return 0
+	}
+	return round3(durationMs(a.total / time.Duration(a.count)))
+}
@@ -0,0 +1,208 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Values accepted by TestConfig.IPVersion
+const (
+	IPAny = ""
+	IPv4  = "4"
+	IPv6  = "6"
+)
+
+// NewTransport returns the transport a run creates when config.Transport is
+// nil, keeping enough idle connections for every worker plus the latency
+// probe. Setting it as Transport shares connections across runs, e.g. when
+// testing repeatedly; call CloseIdleConnections once done.
+func NewTransport(config *TestConfig) (*http.Transport, error) {
+	proxy, err := proxyFunc(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	transport.ForceAttemptHTTP2 = true
+	if config.TLSConfig != nil {
+		transport.TLSClientConfig = config.TLSConfig.Clone()
+	}
+	transport.MaxIdleConnsPerHost = config.NumConnections + 1
+	// Calibration may settle on any count
+	if config.AutoConnections {
+		transport.MaxIdleConnsPerHost = MaxConnections + 1
+	}
+	if transport.MaxIdleConns < transport.MaxIdleConnsPerHost {
+		transport.MaxIdleConns = transport.MaxIdleConnsPerHost
+	}
+	switch {
+	case config.SOCKS5 != "":
+		dial, err := socks5Dial(config)
+		if err != nil {
+			return nil, err
+		}
+		// HTTP_PROXY and friends would bypass the tunnel
+		transport.Proxy = nil
+		transport.DialContext = dial
+	case config.DialContext != nil:
+		transport.DialContext = config.DialContext
+	case config.IPVersion != IPAny:
+		transport.DialContext = forceIPVersion(newDialer(config), config.IPVersion)
+	case config.Resolver != nil:
+		transport.DialContext = newDialer(config).DialContext
+	}
+	return transport, nil
+}
+
+// proxyFunc returns the proxy selector for a ProxyURL, using the standard
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables when it is empty. HTTPS
+// targets are tunneled with CONNECT and credentials in the URL are sent as
+// Proxy-Authorization
+func proxyFunc(proxyURL string) (func(*http.Request) (*url.URL, error), error) {
+	if proxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", proxyURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme must be http or https", proxyURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", proxyURL)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// newDialer returns a dialer with the default transport's timeouts that
+// resolves names with the configured Resolver
+func newDialer(config *TestConfig) *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Resolver:  config.Resolver,
+	}
+}
+
+// NewResolver returns a resolver that sends every DNS query to server, given
+// as "host" or "host:port" (port 53 by default), instead of the system
+// resolver
+func NewResolver(server string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// forceIPVersion returns a dial function restricted to a single address
+// family, failing rather than falling back when the host has no address of
+// that family
+func forceIPVersion(dialer *net.Dialer, version string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network+version, addr)
+		if err != nil {
+			return nil, fmt.Errorf("IPv%s connection to %s failed: %w", version, addr, err)
+		}
+		return conn, nil
+	}
+}
+
+// validIPVersion reports whether version is an accepted IPVersion value
+func validIPVersion(version string) bool {
+	return version == IPAny || version == IPv4 || version == IPv6
+}
+
+// newClient returns a client using the configured transport that sets the
+// User-Agent and adds the configured Headers to every request
+func newClient(config *TestConfig, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &headerTransport{
+			base:      config.Transport,
+			userAgent: config.userAgent(),
+			headers:   config.Headers,
+		},
+		Timeout:       timeout,
+		CheckRedirect: config.checkRedirect,
+	}
+}
+
+// checkRedirect logs every redirect from a test server at info level, and
+// stops at the redirect response unless FollowRedirects is set
+func (c *TestConfig) checkRedirect(req *http.Request, via []*http.Request) error {
+	from := via[len(via)-1].URL.String()
+	if !c.FollowRedirects {
+		c.info("redirect not followed", "from", from, "to", req.URL.String())
+		return http.ErrUseLastResponse
+	}
+	c.info("redirect followed", "from", from, "to", req.URL.String())
+	// The default client's limit
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// headerTransport sets the User-Agent and extra headers on a copy of each
+// request, leaving the caller's request and the shared header map untouched
+type headerTransport struct {
+	base      http.RoundTripper // nil means http.DefaultTransport
+	userAgent string
+	headers   http.Header // wins over userAgent
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	for key, values := range t.headers {
+		req.Header[key] = slices.Clone(values)
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// CloseIdleConnections forwards to the base transport so clients built by
+// newClient can still release their connections
+func (t *headerTransport) CloseIdleConnections() {
+	type closeIdler interface{ CloseIdleConnections() }
+	if c, ok := t.base.(closeIdler); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// verifiesWithSystemRoots reports whether the certificates a TLS server
+// presented verify against the system roots for its name, as they would
+// without a custom TLSConfig
+func verifiesWithSystemRoots(state *tls.ConnectionState) bool {
+	if len(state.PeerCertificates) == 0 {
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       state.ServerName,
+		Intermediates: intermediates,
+	})
+	return err == nil
+}
@@ -0,0 +1,79 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// uploadStream is a request body that repeats payload until deadline or
+// until ctx is done. It lets a single chunked POST keep the link saturated
+// for the whole window.
+type uploadStream struct {
+	ctx      context.Context
+	payload  []byte
+	offset   int
+	deadline time.Time
+}
+
+func (s *uploadStream) Read(p []byte) (int, error) {
+	if s.ctx.Err() != nil || !time.Now().Before(s.deadline) {
+		return 0, io.EOF
+	}
+	n := copy(p, s.payload[s.offset:])
+	s.offset = (s.offset + n) % len(s.payload)
+	return n, nil
+}
+
+// countingReader adds every byte read from r to each of its counters
+type countingReader struct {
+	r        io.Reader
+	counters []*atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	for _, counter := range c.counters {
+		counter.Add(int64(n))
+	}
+	return n, err
+}
+
+// measureUploadSizes runs the upload phase once per UploadChunkSizes entry,
+// recording the throughput of each, and returns the fastest run. Sizes the
+// servers reject are left out; the phase only fails if every size does.
+func measureUploadSizes(ctx context.Context, config *TestConfig) (transferStats, error) {
+	var best transferStats
+	var lastErr error
+	perSize := make(map[int]float64, len(config.UploadChunkSizes))
+	for i, size := range config.UploadChunkSizes {
+		sizeConfig := *config
+		sizeConfig.UploadChunkSize = size
+		// Progress covers all sizes rather than restarting for each
+		if onProgress := config.OnProgress; onProgress != nil {
+			sizeConfig.OnProgress = func(phase string, fraction float64) {
+				onProgress(phase, (float64(i)+fraction)/float64(len(config.UploadChunkSizes)))
+			}
+		}
+		stats, err := measureUploadSpeed(ctx, &sizeConfig)
+		if err != nil {
+			if ctx.Err() != nil {
+				return transferStats{}, err
+			}
+			config.debug("upload chunk size failed", "chunk_size", size, "error", err)
+			lastErr = fmt.Errorf("%d byte chunks: %w", size, err)
+			continue
+		}
+		if len(perSize) == 0 || stats.mbps > best.mbps {
+			best = stats
+		}
+		perSize[size] = stats.mbps
+	}
+	if len(perSize) == 0 {
+		return transferStats{}, lastErr
+	}
+	best.perChunkSize = perSize
+	return best, nil
+}
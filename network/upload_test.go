@@ -0,0 +1,108 @@
+package network
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// TestMeasureUploadSpeedConcurrent runs the upload workers against a real
+// server, so that go test -race checks their shared counters and payload
+func TestMeasureUploadSpeedConcurrent(t *testing.T) {
+	server := httptest.NewServer(NewServerHandler())
+	defer server.Close()
+
+	config := newTestConfig(t)
+	config.UploadServers = []string{server.URL + "/up", server.URL + "/up?second"}
+	config.NumConnections = 8
+	config.UploadChunkSize = 64 * 1024
+	config = prepareTestConfig(t, config)
+
+	stats, err := measureUploadSpeed(context.Background(), config)
+	if err != nil {
+		t.Fatalf("measureUploadSpeed: %v", err)
+	}
+	if stats.bytes <= 0 || stats.mbps <= 0 {
+		t.Errorf("got %d bytes at %v Mbps, want both positive", stats.bytes, stats.mbps)
+	}
+	if stats.errors != 0 {
+		t.Errorf("got %d failed requests, want 0", stats.errors)
+	}
+	if len(stats.perServer) != 2 {
+		t.Fatalf("got throughput for %d servers, want 2", len(stats.perServer))
+	}
+	for _, s := range stats.perServer {
+		if s.Mbps <= 0 {
+			t.Errorf("server %s got %v Mbps, want positive", s.Server, s.Mbps)
+		}
+	}
+}
+
+// TestMeasureUploadSpeedCutOff checks that a server dropping the connection
+// halfway through each body is reported as failing, not as throughput
+func TestMeasureUploadSpeedCutOff(t *testing.T) {
+	const chunkSize = 64 * 1024
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.CopyN(io.Discard, r.Body, chunkSize/2)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	config := newTestConfig(t)
+	config.UploadServers = []string{server.URL}
+	config.UploadChunkSize = chunkSize
+	config = prepareTestConfig(t, config)
+
+	stats, err := measureUploadSpeed(context.Background(), config)
+	if err == nil {
+		t.Error("got no error when every upload was cut off")
+	}
+	if stats.bytes != 0 || stats.mbps != 0 {
+		t.Errorf("got %d bytes at %v Mbps, want 0", stats.bytes, stats.mbps)
+	}
+}
+
+// TestMeasureUploadSpeedRejected checks that only uploads answered with a
+// 2xx status count towards the uploaded bytes
+func TestMeasureUploadSpeedRejected(t *testing.T) {
+	const chunkSize = 64 * 1024
+	var accepted atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if r.URL.Path == "/reject" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		accepted.Add(1)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(t)
+	config.UploadServers = []string{server.URL + "/accept", server.URL + "/reject"}
+	config.UploadChunkSize = chunkSize
+	config = prepareTestConfig(t, config)
+
+	stats, err := measureUploadSpeed(context.Background(), config)
+	if err != nil {
+		t.Fatalf("measureUploadSpeed: %v", err)
+	}
+	if want := accepted.Load() * chunkSize; stats.bytes != want {
+		t.Errorf("got %d bytes, want %d from %d accepted uploads", stats.bytes, want, accepted.Load())
+	}
+	if stats.errors == 0 {
+		t.Error("got no errors for the rejected uploads")
+	}
+	for _, s := range stats.perServer {
+		if s.Server == server.URL+"/reject" && s.Mbps != 0 {
+			t.Errorf("rejecting server got %v Mbps, want 0", s.Mbps)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Validate checks the configuration and makes a single small request to
+// every download, upload, and latency server without running the test.
+// The returned error joins one error per unreachable server.
+func Validate(ctx context.Context, config *TestConfig) error {
+	config, cleanup, err := prepareConfig(config)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	type check struct {
+		kind, method, url string
+	}
+	var checks []check
+	seen := make(map[string]bool)
+	add := func(kind, method, url string) {
+		if key := method + " " + url; !seen[key] {
+			seen[key] = true
+			checks = append(checks, check{kind, method, url})
+		}
+	}
+	if !config.SkipDownload {
+		for _, server := range config.TestServers {
+			add("download", http.MethodHead, server)
+		}
+	}
+	if !config.SkipUpload {
+		for _, server := range config.UploadServers {
+			add("upload", http.MethodPost, server)
+		}
+	}
+	add("latency", http.MethodGet, config.LatencyURL())
+
+	client := newClient(config, config.latencyTimeout())
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			if err := checkServer(ctx, client, c.method, c.url); err != nil {
+				errs[i] = fmt.Errorf("%s server %s: %w", c.kind, c.url, err)
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// checkServer makes one request and fails unless the server responds
+// successfully. HEAD requests the server rejects are retried as GET, reading
+// only the start of the body.
+func checkServer(ctx context.Context, client *http.Client, method, url string) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.CopyN(io.Discard, resp.Body, 1024)
+	resp.Body.Close()
+
+	if method == http.MethodHead && resp.StatusCode == http.StatusMethodNotAllowed {
+		return checkServer(ctx, client, http.MethodGet, url)
+	}
+	if !statusOK(resp) {
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	return nil
+}
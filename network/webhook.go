@@ -0,0 +1,79 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Webhook delivery settings
+const (
+	defaultWebhookTimeout = 10 * time.Second // per attempt, when ctx has no deadline
+	webhookRetries        = 2
+)
+
+// PublishWebhook POSTs the result to url as a ResultJSON document, the
+// format MarshalResultJSON writes. Connection errors and 5xx responses are
+// retried with backoff. The timeout is taken from ctx's deadline, or
+// defaults to 10s per attempt.
+func PublishWebhook(ctx context.Context, url string, r *QualityResult) error {
+	if r == nil {
+		return fmt.Errorf("no result to publish")
+	}
+
+	body, err := json.Marshal(NewResultJSON(r))
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	timeout := time.Duration(0)
+	if _, ok := ctx.Deadline(); !ok {
+		timeout = defaultWebhookTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	// Only failures the server may recover from are retried
+	var lastErr error
+	retry(ctx, webhookRetries, func() error {
+		var retryable bool
+		retryable, lastErr = postWebhook(ctx, client, url, body)
+		if !retryable {
+			return nil
+		}
+		return lastErr
+	})
+	if err := lastErr; err != nil {
+		return fmt.Errorf("webhook %s: %w", url, err)
+	}
+	return nil
+}
+
+// postWebhook makes a single delivery attempt, reporting whether a failure
+// is worth retrying
+func postWebhook(ctx context.Context, client *http.Client, url string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", DefaultUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("server returned %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("server returned %s", resp.Status)
+	}
+	return false, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/P-0001/networkquality/network"
+)
+
+// Output formats selected by the command line flags
+const (
+	formatHuman      = "human"
+	formatPrometheus = "prometheus"
+	formatJSON       = "json"
+	formatQuiet      = "quiet"
+)
+
+// formatReport renders the result in the given output format, without
+// color so it can be written to a file
+func formatReport(result *network.QualityResult, format string) ([]byte, error) {
+	var b bytes.Buffer
+	switch format {
+	case formatPrometheus:
+		if err := network.WritePrometheus(&b, result); err != nil {
+			return nil, err
+		}
+	case formatJSON:
+		data, err := network.MarshalResultJSON(result)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	case formatQuiet:
+		fmt.Fprintf(&b, "down=%.1f up=%.1f lat=%.1f rpm=%d\n",
+			result.DownlinkCapacity, result.UplinkCapacity, result.IdleLatency, result.RPM)
+	default:
+		b.WriteString(result.FormatResult())
+	}
+	return b.Bytes(), nil
+}
+
+// resultOutputs are the destinations every finished result is sent to
+type resultOutputs struct {
+	format     string
+	csvPath    string
+	webhookURL string
+	outputPath string
+}
+
+// record appends result to the CSV file, publishes it to the webhook, and
+// writes it to the output file, returning the formatted report. Webhook
+// failures are only reported on stderr, since publishing is best effort.
+func (o resultOutputs) record(ctx context.Context, result *network.QualityResult) ([]byte, error) {
+	if o.csvPath != "" {
+		if err := network.AppendCSV(o.csvPath, result); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.webhookURL != "" {
+		if err := network.PublishWebhook(ctx, o.webhookURL, result); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	report, err := formatReport(result, o.format)
+	if err != nil {
+		return nil, err
+	}
+	if o.outputPath != "" {
+		if err := writeFileAtomic(o.outputPath, report); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so path never holds a partial write
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".networkquality-*")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if syncErr := tmp.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
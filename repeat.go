@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/P-0001/networkquality/internal/color"
+	"github.com/P-0001/networkquality/network"
+)
+
+// repeatColumns are the metrics shown for each run of -repeat
+var repeatColumns = []struct {
+	name  string
+	value func(r *network.QualityResult) float64
+}{
+	{"Down Mbps", func(r *network.QualityResult) float64 { return r.DownlinkCapacity }},
+	{"Up Mbps", func(r *network.QualityResult) float64 { return r.UplinkCapacity }},
+	{"Latency ms", func(r *network.QualityResult) float64 { return r.IdleLatency }},
+	{"RPM", func(r *network.QualityResult) float64 { return float64(r.RPM) }},
+}
+
+// runRepeat runs the test n times in a row and prints a table of the runs
+// followed by their mean and standard deviation. The mean is what goes to
+// the outputs, and what is printed instead of the table in a machine
+// readable format. Ctrl-C stops early and summarizes the runs that
+// completed; the interrupted run is discarded.
+func runRepeat(config *network.TestConfig, n int, outputs resultOutputs, toStdout bool) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	humanOutput := outputs.format == formatHuman
+	if humanOutput {
+		color.Foreground(color.Cyan, true)
+		fmt.Printf("Running the test %d times (Ctrl-C to stop early)\n", n)
+		color.ResetColor()
+
+		// Each run starts with the idle latency phase
+		run := 0
+		config.OnProgress = func(phase string, fraction float64) {
+			if phase == network.PhaseLatency && fraction == 0 {
+				run++
+				fmt.Printf("\rRun %d of %d...", run, n)
+			}
+		}
+	}
+	mean, results, err := network.RunQualityTestN(ctx, config, n)
+	if humanOutput {
+		fmt.Print("\r                    \r")
+	}
+
+	if len(results) == 0 {
+		color.Foreground(color.Red, true)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		color.ResetColor()
+		os.Exit(1)
+	}
+
+	// Without a mean a run failed, and there is nothing to record
+	var report []byte
+	if mean != nil {
+		var recordErr error
+		report, recordErr = outputs.record(context.WithoutCancel(ctx), mean)
+		if recordErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", recordErr)
+			os.Exit(1)
+		}
+	}
+
+	switch {
+	case !toStdout:
+		// Results only go to the output file
+	case !humanOutput:
+		os.Stdout.Write(report)
+	default:
+		printRepeatTable(results)
+	}
+
+	if err != nil {
+		color.Foreground(color.Yellow, true)
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		color.ResetColor()
+		if !errors.Is(err, network.ErrPartial) {
+			os.Exit(1)
+		}
+	}
+}
+
+// printRepeatTable prints one aligned row per result, then the mean and
+// sample standard deviation of each column
+func printRepeatTable(results []*network.QualityResult) {
+	color.Foreground(color.Magenta, false)
+	fmt.Printf("\n%-8s", "Run")
+	for _, c := range repeatColumns {
+		fmt.Printf(" %12s", c.name)
+	}
+	fmt.Println()
+
+	color.Foreground(color.White, false)
+	for i, r := range results {
+		fmt.Printf("%-8s", strconv.Itoa(i+1))
+		for _, c := range repeatColumns {
+			fmt.Printf(" %12.3f", c.value(r))
+		}
+		fmt.Println()
+	}
+
+	means := make([]float64, len(repeatColumns))
+	stddevs := make([]float64, len(repeatColumns))
+	for i, c := range repeatColumns {
+		values := make([]float64, len(results))
+		for j, r := range results {
+			values[j] = c.value(r)
+		}
+		means[i], stddevs[i] = meanSampleStddev(values)
+	}
+	color.Foreground(color.White, true)
+	for _, row := range []struct {
+		label  string
+		values []float64
+	}{{"Mean", means}, {"Stddev", stddevs}} {
+		fmt.Printf("%-8s", row.label)
+		for _, v := range row.values {
+			fmt.Printf(" %12.3f", v)
+		}
+		fmt.Println()
+	}
+	color.ResetColor()
+}
+
+// meanSampleStddev returns the mean and sample standard deviation of
+// values, with a deviation of 0 for a single value
+func meanSampleStddev(values []float64) (mean, stddev float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	for _, v := range values {
+		stddev += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(stddev / float64(len(values)-1))
+}